@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"slices"
 
 	"cloud.google.com/go/spanner"
 	"google.golang.org/api/option"
@@ -31,6 +32,7 @@ func NewAttachedRuntime(endpoint Endpoint, options ...Option) (*AttachedRuntime,
 	if err != nil {
 		return nil, err
 	}
+	enableSchemaTemplates(opts)
 	return &AttachedRuntime{
 		backend: endpoint.Backend,
 		opts:    opts,
@@ -82,7 +84,12 @@ func finalizeAttachedOptions(endpoint Endpoint, options ...Option) (*emulatorOpt
 		return applyOmniOptionsWithBase(base, options...)
 	case BackendEmulator:
 		return applyOptionsWithBase(base, options...)
+	case BackendInProcess:
+		return applyInProcessOptionsWithBase(base, options...)
 	default:
+		if provider, ok := lookupBackendProvider(endpoint.Backend); ok {
+			return applyProviderOptionsWithBase(endpoint.Backend, provider, base, options...)
+		}
 		return nil, fmt.Errorf("unsupported backend %q", endpoint.Backend)
 	}
 }
@@ -118,9 +125,15 @@ func (a *AttachedRuntime) ClientOptions() []option.ClientOption {
 	}
 }
 
-// Close is a no-op for attached runtimes because this handle does not own the
-// remote backend lifecycle.
-func (a *AttachedRuntime) Close() error { return nil }
+// Close does not stop the remote backend because this handle does not own its
+// lifecycle. It only stops the [WithSchemaTemplates] cache, if enabled, and
+// drops the databases it prepared.
+func (a *AttachedRuntime) Close() error {
+	if a != nil && a.opts != nil {
+		a.opts.schemaTemplates.close()
+	}
+	return nil
+}
 
 func (a *AttachedRuntime) ProjectID() string {
 	if a == nil || a.opts == nil {
@@ -172,7 +185,12 @@ func (a *AttachedRuntime) inheritedOptions(options ...Option) (*emulatorOptions,
 	switch a.backend {
 	case BackendOmni:
 		return applyOmniOptionsWithBase(base, options...)
+	case BackendInProcess:
+		return applyInProcessOptionsWithBase(base, options...)
 	default:
+		if provider, ok := lookupBackendProvider(a.backend); ok {
+			return applyProviderOptionsWithBase(a.backend, provider, base, options...)
+		}
 		return applyOptionsWithBase(base, options...)
 	}
 }
@@ -184,12 +202,31 @@ func preserveAttachedBootstrapOptions(base, source *emulatorOptions) {
 	base.randomDatabaseID = source.randomDatabaseID
 	if len(source.setupDDLs) > 0 {
 		base.setupDDLs = append([]string(nil), source.setupDDLs...)
+		base.setupDDLSources = append([]string(nil), source.setupDDLSources...)
+	}
+	if len(source.migrations) > 0 {
+		base.migrations = slices.Clone(source.migrations)
 	}
 	if len(source.setupFileDescriptorSet) > 0 {
 		base.setupFileDescriptorSet = bytes.Clone(source.setupFileDescriptorSet)
 	}
+	if len(source.setupFixtures) > 0 {
+		base.setupFixtures = slices.Clone(source.setupFixtures)
+	}
+	if len(source.setupMutations) > 0 {
+		base.setupMutations = slices.Clone(source.setupMutations)
+		base.setupMutationBoundaries = slices.Clone(source.setupMutationBoundaries)
+	}
 	if len(source.setupDMLs) > 0 {
 		base.setupDMLs = append([]spanner.Statement(nil), source.setupDMLs...)
+		base.setupDMLSources = append([]string(nil), source.setupDMLSources...)
+		base.setupDMLBoundaries = slices.Clone(source.setupDMLBoundaries)
+	}
+	if source.setupBatchSize > 0 {
+		base.setupBatchSize = source.setupBatchSize
+	}
+	if len(source.setupSteps) > 0 {
+		base.setupSteps = slices.Clone(source.setupSteps)
 	}
 }
 
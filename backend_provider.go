@@ -0,0 +1,303 @@
+package spanemuboost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/api/option"
+)
+
+// BackendProvider starts runtimes for a third-party [Backend] registered with
+// [RegisterBackend], such as an in-house Spanner stand-in.
+//
+// A provider only starts and stops a server for the Spanner gRPC API.
+// spanemuboost creates the instance and database, applies setup, opens
+// clients, and tears them down as it does for the built-in backends, so
+// [Run], [Setup], [NewLazyRuntime], [OpenClients], and [Serve] accept the
+// registered backend.
+type BackendProvider interface {
+	// FinalizeConfig validates config after the options are applied and may
+	// adjust the fields documented as adjustable. It is called when the
+	// runtime is started and again for the options of each [OpenClients]
+	// call, so it must accept its own output.
+	FinalizeConfig(config *BackendConfig) error
+
+	// Start starts a server for config and returns it once it accepts
+	// requests. The server is closed when the runtime is closed, or when
+	// setup fails.
+	Start(ctx context.Context, config BackendConfig) (BackendServer, error)
+}
+
+// BackendServer is a server started by a [BackendProvider].
+//
+// If it also has a Platform(context.Context) (string, error) method,
+// [RuntimePlatform] returns its result; otherwise [RuntimePlatform] returns
+// the backend name.
+type BackendServer interface {
+	// URI returns the gRPC endpoint (host:port).
+	URI() string
+	// ClientOptions returns the [option.ClientOption] values to connect to
+	// URI, such as an endpoint and no authentication.
+	ClientOptions() []option.ClientOption
+	// Close stops the server.
+	Close() error
+}
+
+// BackendConfig is the configuration resolved from the options, as seen by a
+// [BackendProvider].
+type BackendConfig struct {
+	Backend Backend
+
+	// ProjectID, InstanceID, and DatabaseID hold the configured or random IDs,
+	// or the package defaults. FinalizeConfig may replace them, for example
+	// with the fixed IDs of a single-tenant server.
+	ProjectID, InstanceID, DatabaseID string
+
+	// CreateInstance reports whether spanemuboost creates the instance after
+	// Start. FinalizeConfig may clear it for a server with a pre-existing
+	// instance; setting it has no effect.
+	CreateInstance bool
+
+	// The fields below are read-only.
+
+	// ContainerImage is the value of [WithContainerImage], or "".
+	ContainerImage  string
+	DatabaseDialect databasepb.DatabaseDialect
+	// DDLs lists the setup DDLs followed by the migration DDLs.
+	DDLs []string
+	// FileDescriptorSet is the serialized proto descriptor set for CREATE/ALTER
+	// PROTO BUNDLE statements, or nil.
+	FileDescriptorSet []byte
+	// EmulatorFlags lists the emulator gateway flags added by options such as
+	// [EnableFaultInjection]. A provider that cannot honor them should reject
+	// them unless Guardrails is false.
+	EmulatorFlags []string
+	// Guardrails is false when [DisableBackendGuardrails] is used.
+	Guardrails bool
+}
+
+var builtinBackends = []Backend{BackendEmulator, BackendOmni, BackendInProcess, BackendEmulatorBinary}
+
+var backendRegistry struct {
+	mu        sync.RWMutex
+	providers map[Backend]BackendProvider
+}
+
+// RegisterBackend makes provider available under backend. Like
+// database/sql.Register, it is meant to be called from an init function, and
+// it panics if backend is empty or already registered, names a built-in
+// backend, or provider is nil.
+func RegisterBackend(backend Backend, provider BackendProvider) {
+	if backend == "" {
+		panic("spanemuboost: RegisterBackend backend name is empty")
+	}
+	if provider == nil {
+		panic(fmt.Sprintf("spanemuboost: RegisterBackend provider for %q is nil", backend))
+	}
+	if slices.Contains(builtinBackends, backend) {
+		panic(fmt.Sprintf("spanemuboost: RegisterBackend cannot replace built-in backend %q", backend))
+	}
+
+	backendRegistry.mu.Lock()
+	defer backendRegistry.mu.Unlock()
+	if _, dup := backendRegistry.providers[backend]; dup {
+		panic(fmt.Sprintf("spanemuboost: RegisterBackend called twice for backend %q", backend))
+	}
+	if backendRegistry.providers == nil {
+		backendRegistry.providers = make(map[Backend]BackendProvider)
+	}
+	backendRegistry.providers[backend] = provider
+}
+
+// Backends returns the built-in backends followed by the registered backends
+// in sorted order.
+func Backends() []Backend {
+	backendRegistry.mu.RLock()
+	defer backendRegistry.mu.RUnlock()
+	registered := make([]Backend, 0, len(backendRegistry.providers))
+	for backend := range backendRegistry.providers {
+		registered = append(registered, backend)
+	}
+	slices.Sort(registered)
+	return append(slices.Clone(builtinBackends), registered...)
+}
+
+func lookupBackendProvider(backend Backend) (BackendProvider, bool) {
+	backendRegistry.mu.RLock()
+	defer backendRegistry.mu.RUnlock()
+	provider, ok := backendRegistry.providers[backend]
+	return provider, ok
+}
+
+// providedRuntime runs a [BackendServer] started by a registered
+// [BackendProvider].
+type providedRuntime struct {
+	backend  Backend
+	provider BackendProvider
+	server   BackendServer
+	opts     *emulatorOptions
+
+	closeState closeState
+}
+
+func (*providedRuntime) spanemuboostRuntime() {}
+
+// URI returns the gRPC endpoint (host:port) of the server.
+func (r *providedRuntime) URI() string { return r.server.URI() }
+
+// ClientOptions returns the server's [option.ClientOption] values.
+func (r *providedRuntime) ClientOptions() []option.ClientOption { return r.server.ClientOptions() }
+
+// Close stops the server.
+func (r *providedRuntime) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.closeState.close(func() error {
+		if r.opts != nil {
+			r.opts.schemaTemplates.close()
+		}
+		if r.server == nil {
+			return nil
+		}
+		return r.server.Close()
+	})
+}
+
+// ProjectID returns the project ID.
+func (r *providedRuntime) ProjectID() string { return r.opts.projectID }
+
+// InstanceID returns the instance ID.
+func (r *providedRuntime) InstanceID() string { return r.opts.instanceID }
+
+// DatabaseID returns the configured database ID.
+func (r *providedRuntime) DatabaseID() string { return r.opts.databaseID }
+
+// ProjectPath returns the project resource path.
+func (r *providedRuntime) ProjectPath() string { return r.opts.ProjectPath() }
+
+// InstancePath returns the instance resource path.
+func (r *providedRuntime) InstancePath() string { return r.opts.InstancePath() }
+
+// DatabasePath returns the database resource path.
+func (r *providedRuntime) DatabasePath() string { return r.opts.DatabasePath() }
+
+func (r *providedRuntime) inheritedOptions(options ...Option) (*emulatorOptions, error) {
+	base := inheritedRuntimeOptions(r.opts)
+	base.disableBackendGuardrails = r.opts.disableBackendGuardrails
+	return applyProviderOptionsWithBase(r.backend, r.provider, base, options...)
+}
+
+func (r *providedRuntime) runtimePlatform(ctx context.Context) (string, error) {
+	if p, ok := r.server.(interface {
+		Platform(context.Context) (string, error)
+	}); ok {
+		return p.Platform(ctx)
+	}
+	return string(r.backend), nil
+}
+
+func runProvided(ctx context.Context, backend Backend, provider BackendProvider, options ...Option) (Runtime, error) {
+	opts, err := applyProviderOptionsWithBase(backend, provider, &emulatorOptions{}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := startProvided(ctx, backend, provider, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := bootstrap(ctx, opts, r.ClientOptions()...); err != nil {
+		return nil, errors.Join(err, r.Close())
+	}
+	return r, nil
+}
+
+func runProvidedWithClients(ctx context.Context, backend Backend, provider BackendProvider, options ...Option) (*RuntimeEnv, error) {
+	opts, err := applyProviderOptionsWithBase(backend, provider, &emulatorOptions{}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := startProvided(ctx, backend, provider, opts)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := bootstrapAndCreateClientsWithOptions(ctx, r.URI(), opts, r.ClientOptions())
+	if err != nil {
+		return nil, errors.Join(err, r.Close())
+	}
+
+	disableSchemaTeardownUnlessForced(opts, clients)
+
+	return &RuntimeEnv{Clients: clients, runtime: r}, nil
+}
+
+func startProvided(ctx context.Context, backend Backend, provider BackendProvider, opts *emulatorOptions) (*providedRuntime, error) {
+	server, err := provider.Start(ctx, backendConfigOf(backend, opts, opts.emulatorImage))
+	if err != nil {
+		return nil, fmt.Errorf("spanemuboost: start backend %q: %w", backend, err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("spanemuboost: start backend %q: provider returned a nil server", backend)
+	}
+	enableSchemaTemplates(opts)
+	return &providedRuntime{backend: backend, provider: provider, server: server, opts: opts}, nil
+}
+
+// applyProviderOptionsWithBase applies options as for the emulator and then
+// lets provider finalize the result. The container image default of the
+// emulator is not passed to the provider.
+func applyProviderOptionsWithBase(backend Backend, provider BackendProvider, base *emulatorOptions, options ...Option) (*emulatorOptions, error) {
+	opts := *base
+	for _, opt := range options {
+		if err := opt(&opts); err != nil {
+			return nil, err
+		}
+	}
+	image := opts.emulatorImage
+	finalized, err := finalizeOptions(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	config := backendConfigOf(backend, finalized, image)
+	if err := provider.FinalizeConfig(&config); err != nil {
+		return nil, fmt.Errorf("spanemuboost: backend %q: %w", backend, err)
+	}
+	finalized.emulatorImage = image
+	finalized.projectID = config.ProjectID
+	finalized.instanceID = config.InstanceID
+	finalized.databaseID = config.DatabaseID
+	if !config.CreateInstance {
+		finalized.disableCreateInstance = true
+	}
+	if err := validateResourceIDs(finalized); err != nil {
+		return nil, fmt.Errorf("spanemuboost: backend %q: %w", backend, err)
+	}
+	return finalized, nil
+}
+
+func backendConfigOf(backend Backend, opts *emulatorOptions, image string) BackendConfig {
+	ddls := slices.Clone(opts.setupDDLs)
+	for _, m := range opts.migrations {
+		ddls = append(ddls, m.ddls...)
+	}
+	return BackendConfig{
+		Backend:           backend,
+		ProjectID:         opts.projectID,
+		InstanceID:        opts.instanceID,
+		DatabaseID:        opts.databaseID,
+		CreateInstance:    !opts.disableCreateInstance,
+		ContainerImage:    image,
+		DatabaseDialect:   opts.databaseDialect,
+		DDLs:              ddls,
+		FileDescriptorSet: slices.Clone(opts.setupFileDescriptorSet),
+		EmulatorFlags:     slices.Clone(opts.gatewayFlags),
+		Guardrails:        !opts.disableBackendGuardrails,
+	}
+}
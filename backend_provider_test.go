@@ -0,0 +1,129 @@
+package spanemuboost
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/api/option/internaloption"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const testProvidedBackend Backend = "test-provided"
+
+func init() {
+	RegisterBackend(testProvidedBackend, testBackendProvider{})
+}
+
+// testBackendProvider serves the in-process fakes as a third-party backend
+// with a fixed instance.
+type testBackendProvider struct{}
+
+func (testBackendProvider) FinalizeConfig(config *BackendConfig) error {
+	if config.Guardrails && config.DatabaseDialect == databasepb.DatabaseDialect_POSTGRESQL {
+		return errors.New("the PostgreSQL dialect is unsupported")
+	}
+	config.InstanceID = "fixed-instance"
+	return nil
+}
+
+func (testBackendProvider) Start(context.Context, BackendConfig) (BackendServer, error) {
+	server, err := newInProcessServer()
+	if err != nil {
+		return nil, err
+	}
+	return testBackendServer{server}, nil
+}
+
+type testBackendServer struct{ server *inProcessServer }
+
+func (s testBackendServer) URI() string { return s.server.listener.Addr().String() }
+
+func (s testBackendServer) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint("passthrough:///" + s.URI()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		internaloption.SkipDialSettingsValidation(),
+	}
+}
+
+func (s testBackendServer) Close() error { return s.server.close() }
+
+func TestRegisteredBackend(t *testing.T) {
+	runtime := NewLazyRuntime(testProvidedBackend, EnableInstanceAutoConfigOnly(), WithInstanceID("ignored-instance"))
+	t.Cleanup(func() {
+		if err := runtime.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	clients := SetupClients(t, runtime,
+		WithRandomDatabaseID(),
+		ForceSchemaTeardown(),
+		WithSetupDDLs(inProcessTestDDLs),
+		WithSetupMutations([]*spanner.Mutation{
+			spanner.Insert("Singers", []string{"SingerId", "Name"}, []any{int64(1), "Alice"}),
+		}),
+	)
+	if diff := cmp.Diff([]string{"Alice"}, readSingerNames(t, clients.Client)); diff != "" {
+		t.Fatalf("rows mismatch (-want +got):\n%s", diff)
+	}
+	if got := clients.InstanceID; got != "fixed-instance" {
+		t.Fatalf("InstanceID = %q, want the instance set by FinalizeConfig", got)
+	}
+
+	started, err := runtime.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := EndpointFromRuntime(started)
+	if err != nil || endpoint.Backend != testProvidedBackend {
+		t.Fatalf("EndpointFromRuntime() = %+v, %v; want backend %q", endpoint, err, testProvidedBackend)
+	}
+	platform, err := RuntimePlatform(t.Context(), runtime)
+	if err != nil || platform != string(testProvidedBackend) {
+		t.Fatalf("RuntimePlatform() = %q, %v; want %q", platform, err, testProvidedBackend)
+	}
+}
+
+func TestRegisteredBackendFinalizeConfig(t *testing.T) {
+	_, err := Run(t.Context(), testProvidedBackend, WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL))
+	if err == nil || !strings.Contains(err.Error(), "PostgreSQL dialect is unsupported") || !strings.Contains(err.Error(), string(testProvidedBackend)) {
+		t.Fatalf("Run() error = %v, want the provider's guardrail error", err)
+	}
+
+	runtime := Setup(t, testProvidedBackend, EnableInstanceAutoConfigOnly())
+	if _, err := OpenClients(t.Context(), runtime, WithRandomDatabaseID(), WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL)); err == nil {
+		t.Fatal("OpenClients() with the PostgreSQL dialect error = nil, want the provider's guardrail error")
+	}
+}
+
+func TestRegisterBackend(t *testing.T) {
+	if !slices.Contains(Backends(), testProvidedBackend) {
+		t.Fatalf("Backends() = %v, want %q", Backends(), testProvidedBackend)
+	}
+
+	for _, backend := range []Backend{"", BackendEmulator, testProvidedBackend} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterBackend(%q) did not panic", backend)
+				}
+			}()
+			RegisterBackend(backend, testBackendProvider{})
+		}()
+	}
+
+	cfg, err := ParseServeArgs([]string{string(testProvidedBackend), "--endpoint-file", "endpoint.json"})
+	if err != nil || cfg.Backend != testProvidedBackend {
+		t.Fatalf("ParseServeArgs() = %+v, %v; want backend %q", cfg, err, testProvidedBackend)
+	}
+}
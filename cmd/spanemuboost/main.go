@@ -26,6 +26,18 @@ func main() {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
+	case "dump":
+		if err := runDump(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "load":
+		if err := runLoad(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "validate-ddl":
+		os.Exit(runValidateDDL(os.Args[2:]))
 	default:
 		usage()
 		os.Exit(2)
@@ -53,21 +65,75 @@ func runStop(args []string) error {
 	return spanemuboost.StopFromConfig(context.Background(), cfg)
 }
 
+func runDump(args []string) error {
+	cfg, err := spanemuboost.ParseDumpArgs(args)
+	if err != nil {
+		return err
+	}
+	return spanemuboost.DumpFromConfig(context.Background(), cfg)
+}
+
+func runLoad(args []string) error {
+	cfg, err := spanemuboost.ParseLoadArgs(args)
+	if err != nil {
+		return err
+	}
+	return spanemuboost.LoadFromConfig(context.Background(), cfg)
+}
+
+// runValidateDDL returns 0 when every statement applies, 1 when a statement
+// is rejected, 2 on usage errors, and 3 when the validation could not run.
+func runValidateDDL(args []string) int {
+	cfg, err := spanemuboost.ParseValidateDDLArgs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := spanemuboost.ValidateDDLFromConfig(ctx, cfg, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 3
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
 func usage() {
 	fmt.Fprintf(os.Stderr, `spanemuboost manages long-lived Spanner test backends.
 
 Usage:
-  spanemuboost serve <emulator|omni> --endpoint-file path [--pid-file path] [--with-default-database]
+  spanemuboost serve <emulator|omni> --endpoint-file path [--pid-file path] [--state-file path] [--with-default-database]
   spanemuboost stop --endpoint-file path [--pid-file path]
+  spanemuboost dump [--endpoint-file path] --file path [--database id]...
+  spanemuboost load [--endpoint-file path] --file path
+  spanemuboost validate-ddl [--endpoint-file path] [--dialect googlesql|postgresql] [--migrations dir] [--print-schema] [--json] [file]...
 
 Examples:
   spanemuboost serve omni --endpoint-file /tmp/omni-endpoint.json
   spanemuboost serve omni --endpoint-file /tmp/omni-endpoint.json --with-default-database
+  spanemuboost serve emulator --endpoint-file /tmp/emulator-endpoint.json --state-file /tmp/emulator-state.json
+  spanemuboost dump --endpoint-file /tmp/omni-endpoint.json --file /tmp/omni-dump.json
+  spanemuboost load --endpoint-file /tmp/omni-endpoint.json --file /tmp/omni-dump.json
+  spanemuboost validate-ddl --migrations db/migrations --print-schema
+  spanemuboost validate-ddl --dialect postgresql --json schema.sql
   spanemuboost stop --endpoint-file /tmp/omni-endpoint.json
   SPANEMUBOOST_ENDPOINT_FILE=/tmp/omni-endpoint.json go test ./...
 
 The endpoint file is owned by serve: it is written on startup and removed on
 exit. Unset SPANEMUBOOST_ENDPOINT_FILE after stopping the lifecycle manager.
 
+dump and load connect to the endpoint file, or to SPANEMUBOOST_ENDPOINT_FILE
+and the URI env vars when --endpoint-file is omitted. With --state-file, serve
+saves all databases on shutdown and restores them on the next startup.
+
+validate-ddl applies the migrations and files to a throwaway database on the
+configured endpoint, or on a new emulator, and reports the first rejected
+statement as file:line. It exits 1 when a statement is rejected, 2 on usage
+errors, and 3 when the validation could not run.
+
 `)
 }
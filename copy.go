@@ -0,0 +1,154 @@
+package spanemuboost
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// CopyRowFunc inspects a row copied by [CopyDatabase] before it is inserted.
+// row maps column names to values and can be modified in place, for example to
+// mask personal data; the types of the values must not change. Returning false
+// skips the row.
+//
+// Tables are visited with parent and referenced tables first, so a function
+// can remember the keys it kept and skip the rows that would refer to rows it
+// skipped.
+type CopyRowFunc func(table string, row map[string]spanner.GenericColumnValue) (bool, error)
+
+// WithCopyRows makes [CopyDatabase] copy up to limit rows of each table, or
+// all rows when limit is 0. Without it, only the schema is copied.
+//
+// With a limit, rows of interleaved or referencing tables may refer to parent
+// or referenced rows that were not copied, which fails the copy. Use
+// [WithCopyRowFunc] to skip such rows.
+func WithCopyRows(limit int) Option {
+	return func(opts *emulatorOptions) error {
+		if limit < 0 {
+			return fmt.Errorf("WithCopyRows: limit must be >= 0, got %d", limit)
+		}
+		opts.copyRows = true
+		opts.copyRowLimit = limit
+		return nil
+	}
+}
+
+// WithCopyRowFunc sets a function that filters or transforms the rows copied
+// by [CopyDatabase]. It has no effect without [WithCopyRows].
+func WithCopyRowFunc(f CopyRowFunc) Option {
+	return func(opts *emulatorOptions) error {
+		opts.copyRowFunc = f
+		return nil
+	}
+}
+
+// CopyDatabase creates a database on dst with the schema of the database of
+// src: its DDL as returned by GetDatabaseDdl, its proto bundle descriptors, and
+// its dialect. Use [WithCopyRows] to copy rows too. src can be on another
+// endpoint, for example a dev emulator or Omni, to reproduce a database
+// locally.
+//
+// The new database gets the ID of the source database unless [WithDatabaseID]
+// or [WithRandomDatabaseID] is given, and follows the teardown policy of
+// [OpenClients] with database auto-creation enabled. CopyDatabase fails if a
+// database with that ID already exists on dst, for example when dst is the
+// runtime of src and no other ID is given. Setup DDLs, data, and
+// steps in options are applied on top of the copied schema and rows. Call
+// [Clients.Close] to close the clients.
+func CopyDatabase(ctx context.Context, src *Clients, dst RuntimeHandle, options ...Option) (*Clients, error) {
+	if src == nil {
+		return nil, fmt.Errorf("spanemuboost: source clients is nil")
+	}
+	r, err := resolveRuntime(ctx, dst)
+	if err != nil {
+		return nil, err
+	}
+	source, err := readDatabaseSchema(ctx, src.DatabaseClient, src.DatabasePath())
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := r.inheritedOptions(slices.Concat([]Option{
+		WithDatabaseID(src.DatabaseID),
+		EnableDatabaseAutoConfigOnly(),
+		WithDatabaseDialect(source.dialect),
+	}, options)...)
+	if err != nil {
+		return nil, err
+	}
+	opts.requireNewDatabase = true
+
+	source.prependTo(opts)
+	if opts.copyRows {
+		if err := src.requireInformationSchema("WithCopyRows"); err != nil {
+			return nil, err
+		}
+		snap, err := snapshotDatabase(ctx, src.Client, source.dialect, opts.copyRowLimit)
+		if err != nil {
+			return nil, err
+		}
+		if opts.copyRowFunc != nil {
+			if err := opts.copyRowFunc.apply(snap); err != nil {
+				return nil, err
+			}
+		}
+		if opts.copiedRows, err = snapshotMutations(snap); err != nil {
+			return nil, err
+		}
+	}
+
+	return bootstrapAndCreateClientsWithOptions(ctx, r.URI(), opts, r.ClientOptions())
+}
+
+// CopyDatabaseFromEndpoint is like [CopyDatabase], but reads the database
+// databaseID from the instance of endpoint.
+func CopyDatabaseFromEndpoint(ctx context.Context, endpoint Endpoint, databaseID string, dst RuntimeHandle, options ...Option) (*Clients, error) {
+	runtime, err := NewAttachedRuntime(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		logCloseError("close source runtime after copy", runtime.Close())
+	}()
+	src, err := OpenClients(ctx, runtime, WithDatabaseID(databaseID), DisableAutoConfig())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		logCloseError("close source clients after copy", src.Close())
+	}()
+	return CopyDatabase(ctx, src, dst, options...)
+}
+
+// apply calls f for each row of snap, dropping the rows it skips and keeping
+// the values it sets.
+func (f CopyRowFunc) apply(snap *Snapshot) error {
+	for _, table := range snap.Tables {
+		rows := table.Rows[:0]
+		for _, row := range table.Rows {
+			values := make(map[string]spanner.GenericColumnValue, len(row))
+			for i, column := range table.Columns {
+				values[column] = spanner.GenericColumnValue{Type: table.Types[i], Value: row[i]}
+			}
+			keep, err := f(table.Name, values)
+			if err != nil {
+				return fmt.Errorf("copy row of table %s: %w", table.Name, err)
+			}
+			if !keep {
+				continue
+			}
+			for i, column := range table.Columns {
+				row[i] = values[column].Value
+				if row[i] == nil {
+					row[i] = structpb.NewNullValue()
+				}
+			}
+			rows = append(rows, row)
+		}
+		table.Rows = rows
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package spanemuboost
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCopyRowFuncApply(t *testing.T) {
+	stringType := &sppb.Type{Code: sppb.TypeCode_STRING}
+	snap := &Snapshot{Tables: []*SnapshotTable{{
+		Name:    "Users",
+		Columns: []string{"Email", "UserId"},
+		Types:   []*sppb.Type{stringType, {Code: sppb.TypeCode_INT64}},
+		Rows: [][]*structpb.Value{
+			{structpb.NewStringValue("alice@example.com"), structpb.NewStringValue("1")},
+			{structpb.NewStringValue("bob@example.com"), structpb.NewStringValue("2")},
+		},
+	}}}
+
+	f := CopyRowFunc(func(table string, row map[string]spanner.GenericColumnValue) (bool, error) {
+		if row["UserId"].Value.GetStringValue() == "2" {
+			return false, nil
+		}
+		row["Email"] = spanner.GenericColumnValue{Type: stringType, Value: structpb.NewStringValue("masked")}
+		return true, nil
+	})
+	if err := f.apply(snap); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	want := [][]*structpb.Value{{structpb.NewStringValue("masked"), structpb.NewStringValue("1")}}
+	if diff := cmp.Diff(want, snap.Tables[0].Rows, protocmp.Transform()); diff != "" {
+		t.Fatalf("rows mismatch (-want +got):\n%s", diff)
+	}
+
+	errBoom := errors.New("boom")
+	err := CopyRowFunc(func(string, map[string]spanner.GenericColumnValue) (bool, error) {
+		return false, errBoom
+	}).apply(snap)
+	if !errors.Is(err, errBoom) || !strings.Contains(err.Error(), "table Users") {
+		t.Fatalf("apply() error = %v, want wrapped boom naming the table", err)
+	}
+}
+
+func TestWithCopyRowsRejectsNegativeLimit(t *testing.T) {
+	_, err := applyOptions(WithCopyRows(-1))
+	if err == nil || !strings.Contains(err.Error(), "WithCopyRows: limit must be >= 0") {
+		t.Fatalf("applyOptions() error = %v, want limit error", err)
+	}
+}
+
+func TestCopyDatabaseFromEndpoint(t *testing.T) {
+	src := SetupEmulatorWithClients(t,
+		WithSetupDDLs([]string{"CREATE TABLE Users (UserId INT64, Email STRING(MAX)) PRIMARY KEY (UserId)"}),
+		WithSetupRawDMLs([]string{"INSERT INTO Users (UserId, Email) VALUES (1, 'a@example.com'), (2, 'b@example.com'), (3, 'c@example.com')"}),
+	)
+	endpoint, err := EndpointFromRuntime(src.Emulator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+
+	clients, err := CopyDatabaseFromEndpoint(t.Context(), endpoint, src.Clients.DatabaseID, dst,
+		WithCopyRows(2),
+		WithCopyRowFunc(func(table string, row map[string]spanner.GenericColumnValue) (bool, error) {
+			email := row["Email"]
+			email.Value = structpb.NewStringValue("user@example.invalid")
+			row["Email"] = email
+			return true, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("CopyDatabaseFromEndpoint() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := clients.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+
+	var got []string
+	err = clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT Email FROM Users ORDER BY UserId")).Do(func(r *spanner.Row) error {
+		var email string
+		if err := r.Column(0, &email); err != nil {
+			return err
+		}
+		got = append(got, email)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"user@example.invalid", "user@example.invalid"}, got); diff != "" {
+		t.Fatalf("copied rows mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCopyDatabaseAppliesFixturesAfterCopiedRows(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	src := SetupClients(t, emu,
+		WithRandomDatabaseID(),
+		WithSetupDDLs([]string{
+			"CREATE TABLE Users (UserId INT64, Email STRING(MAX)) PRIMARY KEY (UserId)",
+			"CREATE TABLE Orders (OrderId INT64, UserId INT64, CONSTRAINT FK_OrdersUsers FOREIGN KEY (UserId) REFERENCES Users (UserId)) PRIMARY KEY (OrderId)",
+		}),
+		WithSetupRawDMLs([]string{"INSERT INTO Users (UserId, Email) VALUES (1, 'a@example.com')"}),
+	)
+
+	// The order refers to the copied and masked user, so it can only be
+	// inserted after the copied rows.
+	clients, err := CopyDatabase(t.Context(), src, emu,
+		WithRandomDatabaseID(),
+		WithCopyRows(0),
+		WithCopyRowFunc(func(table string, row map[string]spanner.GenericColumnValue) (bool, error) {
+			if table == "Users" {
+				email := row["Email"]
+				email.Value = structpb.NewStringValue("user@example.invalid")
+				row["Email"] = email
+			}
+			return true, nil
+		}),
+		WithSetupFixtures(fstest.MapFS{
+			"Orders.json": {Data: []byte(`[{"OrderId": 10, "UserId": 1}]`)},
+		}, "Orders.json"),
+	)
+	if err != nil {
+		t.Fatalf("CopyDatabase() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := clients.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+
+	var got []string
+	err = clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT u.Email FROM Orders o JOIN Users u USING (UserId)")).Do(func(r *spanner.Row) error {
+		var email string
+		if err := r.Column(0, &email); err != nil {
+			return err
+		}
+		got = append(got, email)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"user@example.invalid"}, got); diff != "" {
+		t.Fatalf("orders of copied users mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCopyDatabaseRejectsExistingDatabase(t *testing.T) {
+	runtime := Setup(t, BackendInProcess, EnableInstanceAutoConfigOnly())
+	src := SetupClients(t, runtime, WithRandomDatabaseID(), WithSetupDDLs(inProcessTestDDLs))
+
+	_, err := CopyDatabase(t.Context(), src, runtime)
+	if err == nil || !strings.Contains(err.Error(), "database "+src.DatabasePath()+" already exists") {
+		t.Fatalf("CopyDatabase() onto the source database error = %v, want already exists", err)
+	}
+
+	copied, err := CopyDatabase(t.Context(), src, runtime, WithRandomDatabaseID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := copied.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	if copied.DatabaseID == src.DatabaseID {
+		t.Fatalf("CopyDatabase(WithRandomDatabaseID()) reused the source database %s", src.DatabaseID)
+	}
+}
@@ -0,0 +1,361 @@
+package spanemuboost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// dumpFormatVersion is the version of the dump file format written by
+// [WriteDumpFile]. [ReadDumpFile] rejects other versions.
+const dumpFormatVersion = 1
+
+// Dump is the portable form of the databases of one instance, written by
+// [DumpRuntime] and `spanemuboost dump` and loaded by [LoadRuntime] and
+// `spanemuboost load`.
+type Dump struct {
+	Version   int             `json:"version"`
+	Databases []*DatabaseDump `json:"databases"`
+}
+
+// DatabaseDump is the portable form of one database: its schema as returned by
+// GetDatabaseDdl and its rows.
+//
+// Sequence counters, change stream records, and version history are not part
+// of a dump.
+type DatabaseDump struct {
+	DatabaseID string                     `json:"database_id"`
+	Dialect    databasepb.DatabaseDialect `json:"dialect"`
+	DDLs       []string                   `json:"ddls"`
+	// ProtoDescriptors holds the serialized FileDescriptorSet of the proto
+	// bundle, if the database has one.
+	ProtoDescriptors []byte    `json:"proto_descriptors,omitempty"`
+	Data             *Snapshot `json:"data"`
+}
+
+// MarshalJSON implements [json.Marshaler]. The dialect is encoded by name.
+func (d *DatabaseDump) MarshalJSON() ([]byte, error) {
+	type plain DatabaseDump
+	return json.Marshal(struct {
+		*plain
+		Dialect string `json:"dialect"`
+	}{(*plain)(d), d.Dialect.String()})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (d *DatabaseDump) UnmarshalJSON(data []byte) error {
+	type plain DatabaseDump
+	in := struct {
+		*plain
+		Dialect string `json:"dialect"`
+	}{plain: (*plain)(d)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	dialect, ok := databasepb.DatabaseDialect_value[in.Dialect]
+	if !ok && in.Dialect != "" {
+		return fmt.Errorf("database %s: unknown dialect %q", d.DatabaseID, in.Dialect)
+	}
+	d.Dialect = databasepb.DatabaseDialect(dialect)
+	return nil
+}
+
+// DumpDatabase reads the schema and all rows of the database of clients.
+func DumpDatabase(ctx context.Context, clients *Clients) (*DatabaseDump, error) {
+	if clients == nil {
+		return nil, fmt.Errorf("spanemuboost: clients is nil")
+	}
+	if err := clients.requireInformationSchema("DumpDatabase"); err != nil {
+		return nil, err
+	}
+	return dumpDatabase(ctx, clients.DatabaseClient, clients.Client, clients.DatabasePath())
+}
+
+func dumpDatabase(ctx context.Context, dbCli *database.DatabaseAdminClient, client *spanner.Client, databasePath string) (*DatabaseDump, error) {
+	schema, err := readDatabaseSchema(ctx, dbCli, databasePath)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := snapshotDatabase(ctx, client, schema.dialect, 0)
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", databasePath, err)
+	}
+	return &DatabaseDump{
+		DatabaseID:       path.Base(databasePath),
+		Dialect:          schema.dialect,
+		DDLs:             schema.ddls,
+		ProtoDescriptors: schema.protoDescriptors,
+		Data:             snap,
+	}, nil
+}
+
+// LoadDatabase creates the database of dump in the instance of runtime, applies
+// its schema, and inserts its rows. options are applied after the ones derived
+// from dump, so [WithDatabaseID] loads the dump under another ID.
+//
+// If the database already exists, its schema is left as is and its rows are
+// replaced with the rows of dump.
+//
+// The database is not dropped when the returned clients are closed unless
+// [ForceSchemaTeardown] is given. Call [Clients.Close] to close the clients.
+func LoadDatabase(ctx context.Context, runtime RuntimeHandle, dump *DatabaseDump, options ...Option) (*Clients, error) {
+	if dump == nil {
+		return nil, fmt.Errorf("spanemuboost: database dump is nil")
+	}
+	base := []Option{
+		WithDatabaseID(dump.DatabaseID),
+		EnableDatabaseAutoConfigOnly(),
+		SkipSchemaTeardown(),
+		WithDatabaseDialect(dump.Dialect),
+		WithSetupDDLs(dump.DDLs),
+		WithSetupRawFileDescriptorSet(dump.ProtoDescriptors),
+	}
+	if dump.Data != nil {
+		base = append(base, WithSetupSteps(FuncStep(func(ctx context.Context, clients *Clients) error {
+			return clients.Restore(ctx, dump.Data)
+		})))
+	}
+	return OpenClients(ctx, runtime, slices.Concat(base, options)...)
+}
+
+// builtinDatabaseIDs are databases that a backend creates by itself and that
+// [DumpRuntime] skips unless they are requested explicitly.
+var builtinDatabaseIDs = []string{"spanner-info"}
+
+// DumpRuntime dumps the databases in the instance of runtime. When
+// databaseIDs is empty, every database is dumped except built-in ones such as
+// the spanner-info database of Omni.
+func DumpRuntime(ctx context.Context, runtime RuntimeHandle, databaseIDs ...string) (*Dump, error) {
+	r, err := resolveRuntime(ctx, runtime)
+	if err != nil {
+		return nil, err
+	}
+	if isInProcessRuntime(r) {
+		return nil, errInformationSchemaUnsupported("DumpRuntime")
+	}
+	clientOpts := r.ClientOptions()
+	dbCli, err := database.NewDatabaseAdminClient(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		logCloseError("close database admin client", dbCli.Close())
+	}()
+
+	dump := &Dump{Version: dumpFormatVersion}
+	it := dbCli.ListDatabases(ctx, &databasepb.ListDatabasesRequest{Parent: r.InstancePath()})
+	for {
+		db, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list databases: %w", err)
+		}
+		id := path.Base(db.GetName())
+		if len(databaseIDs) > 0 && !slices.Contains(databaseIDs, id) ||
+			len(databaseIDs) == 0 && slices.Contains(builtinDatabaseIDs, id) {
+			continue
+		}
+		d, err := dumpDatabaseWithNewClient(ctx, dbCli, db.GetName(), clientOpts)
+		if err != nil {
+			return nil, err
+		}
+		dump.Databases = append(dump.Databases, d)
+	}
+	for _, id := range databaseIDs {
+		if !slices.ContainsFunc(dump.Databases, func(d *DatabaseDump) bool { return d.DatabaseID == id }) {
+			return nil, fmt.Errorf("spanemuboost: database %q not found in %s", id, r.InstancePath())
+		}
+	}
+	return dump, nil
+}
+
+func dumpDatabaseWithNewClient(ctx context.Context, dbCli *database.DatabaseAdminClient, databasePath string, clientOpts []option.ClientOption) (*DatabaseDump, error) {
+	client, err := spanner.NewClientWithConfig(ctx, databasePath,
+		minimalBootstrapClientConfig(spanner.ClientConfig{DisableNativeMetrics: true}),
+		clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return dumpDatabase(ctx, dbCli, client, databasePath)
+}
+
+// LoadRuntime loads every database of dump into the instance of runtime with
+// [LoadDatabase].
+func LoadRuntime(ctx context.Context, runtime RuntimeHandle, dump *Dump) error {
+	if dump == nil {
+		return fmt.Errorf("spanemuboost: dump is nil")
+	}
+	for _, d := range dump.Databases {
+		clients, err := LoadDatabase(ctx, runtime, d)
+		if err != nil {
+			return fmt.Errorf("spanemuboost: load database %s: %w", d.DatabaseID, err)
+		}
+		if err := clients.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadDumpFile loads a [Dump] from a JSON file written by [WriteDumpFile] or
+// `spanemuboost dump`.
+func ReadDumpFile(path string) (*Dump, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("spanemuboost: read dump file %q: %w", path, err)
+	}
+	var dump Dump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("spanemuboost: parse dump file %q: %w", path, err)
+	}
+	if dump.Version != dumpFormatVersion {
+		return nil, fmt.Errorf("spanemuboost: dump file %q has unsupported version %d, want %d", path, dump.Version, dumpFormatVersion)
+	}
+	return &dump, nil
+}
+
+// WriteDumpFile writes dump as JSON with mode 0600.
+func WriteDumpFile(path string, dump *Dump) error {
+	if dump == nil {
+		return fmt.Errorf("spanemuboost: dump is nil")
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("spanemuboost: marshal dump: %w", err)
+	}
+	data = append(data, '\n')
+	return writeFileAtomic(path, "dump", data)
+}
+
+// DumpConfig configures [DumpFromConfig].
+type DumpConfig struct {
+	EndpointFile string
+	File         string
+	Databases    []string
+}
+
+// DumpFromConfig dumps the databases of a running backend to DumpConfig.File.
+// The backend is identified by DumpConfig.EndpointFile, or by [LoadEndpoint]
+// when it is empty.
+func DumpFromConfig(ctx context.Context, cfg DumpConfig) error {
+	runtime, err := attachFromEndpointFile(cfg.EndpointFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		logCloseError("close attached runtime after dump", runtime.Close())
+	}()
+	dump, err := DumpRuntime(ctx, runtime, cfg.Databases...)
+	if err != nil {
+		return err
+	}
+	return WriteDumpFile(cfg.File, dump)
+}
+
+// LoadConfig configures [LoadFromConfig].
+type LoadConfig struct {
+	EndpointFile string
+	File         string
+}
+
+// LoadFromConfig loads the databases of LoadConfig.File into a running backend.
+// The backend is identified by LoadConfig.EndpointFile, or by [LoadEndpoint]
+// when it is empty.
+func LoadFromConfig(ctx context.Context, cfg LoadConfig) error {
+	dump, err := ReadDumpFile(cfg.File)
+	if err != nil {
+		return err
+	}
+	runtime, err := attachFromEndpointFile(cfg.EndpointFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		logCloseError("close attached runtime after load", runtime.Close())
+	}()
+	return LoadRuntime(ctx, runtime, dump)
+}
+
+func attachFromEndpointFile(path string) (*AttachedRuntime, error) {
+	if strings.TrimSpace(path) == "" {
+		return NewAttachedRuntimeFromEnv()
+	}
+	endpoint, err := ReadEndpointFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewAttachedRuntime(endpoint)
+}
+
+// ParseDumpArgs parses `spanemuboost dump [--endpoint-file path] --file path [--database id]...`.
+func ParseDumpArgs(args []string) (DumpConfig, error) {
+	cfg := DumpConfig{}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--endpoint-file", "-o":
+			if i+1 >= len(args) {
+				return DumpConfig{}, fmt.Errorf("--endpoint-file requires a value")
+			}
+			cfg.EndpointFile = args[i+1]
+			i++
+		case "--file", "-f":
+			if i+1 >= len(args) {
+				return DumpConfig{}, fmt.Errorf("--file requires a value")
+			}
+			cfg.File = args[i+1]
+			i++
+		case "--database":
+			if i+1 >= len(args) {
+				return DumpConfig{}, fmt.Errorf("--database requires a value")
+			}
+			cfg.Databases = append(cfg.Databases, args[i+1])
+			i++
+		default:
+			return DumpConfig{}, fmt.Errorf("unknown argument %q", args[i])
+		}
+	}
+	if cfg.File == "" {
+		return DumpConfig{}, fmt.Errorf("usage: spanemuboost dump [--endpoint-file path] --file path [--database id]...")
+	}
+	return cfg, nil
+}
+
+// ParseLoadArgs parses `spanemuboost load [--endpoint-file path] --file path`.
+func ParseLoadArgs(args []string) (LoadConfig, error) {
+	cfg := LoadConfig{}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--endpoint-file", "-o":
+			if i+1 >= len(args) {
+				return LoadConfig{}, fmt.Errorf("--endpoint-file requires a value")
+			}
+			cfg.EndpointFile = args[i+1]
+			i++
+		case "--file", "-f":
+			if i+1 >= len(args) {
+				return LoadConfig{}, fmt.Errorf("--file requires a value")
+			}
+			cfg.File = args[i+1]
+			i++
+		default:
+			return LoadConfig{}, fmt.Errorf("unknown argument %q", args[i])
+		}
+	}
+	if cfg.File == "" {
+		return LoadConfig{}, fmt.Errorf("usage: spanemuboost load [--endpoint-file path] --file path")
+	}
+	return cfg, nil
+}
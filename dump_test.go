@@ -0,0 +1,140 @@
+package spanemuboost
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestDatabaseDumpJSON(t *testing.T) {
+	want := &Dump{
+		Version: dumpFormatVersion,
+		Databases: []*DatabaseDump{{
+			DatabaseID:       "db",
+			Dialect:          databasepb.DatabaseDialect_POSTGRESQL,
+			DDLs:             []string{"CREATE TABLE t (pk bigint PRIMARY KEY)"},
+			ProtoDescriptors: []byte{1, 2, 3},
+			Data:             &Snapshot{Tables: []*SnapshotTable{{Name: "t", Columns: []string{"pk"}}}},
+		}},
+	}
+	path := filepath.Join(t.TempDir(), "dump.json")
+	if err := WriteDumpFile(path, want); err != nil {
+		t.Fatalf("WriteDumpFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"dialect": "POSTGRESQL"`) {
+		t.Fatalf("dump file = %s, want the dialect encoded by name", data)
+	}
+	got, err := ReadDumpFile(path)
+	if err != nil {
+		t.Fatalf("ReadDumpFile() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Fatalf("dump mismatch after round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadDumpFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "unsupported version", data: `{"version": 2}`, want: "unsupported version 2"},
+		{name: "unknown dialect", data: `{"version": 1, "databases": [{"database_id": "db", "dialect": "MYSQL"}]}`, want: `unknown dialect "MYSQL"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dump.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadDumpFile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ReadDumpFile() error = %v, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDumpArgs(t *testing.T) {
+	cfg, err := ParseDumpArgs([]string{"--endpoint-file", "/tmp/emulator.json", "--file", "/tmp/dump.json", "--database", "a", "--database", "b"})
+	if err != nil {
+		t.Fatalf("ParseDumpArgs() error = %v", err)
+	}
+	want := DumpConfig{EndpointFile: "/tmp/emulator.json", File: "/tmp/dump.json", Databases: []string{"a", "b"}}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Fatalf("ParseDumpArgs() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseDumpArgs([]string{"--endpoint-file", "/tmp/emulator.json"}); err == nil || !strings.Contains(err.Error(), "usage:") {
+		t.Fatalf("ParseDumpArgs() without --file error = %v, want usage error", err)
+	}
+}
+
+func TestParseLoadArgs(t *testing.T) {
+	cfg, err := ParseLoadArgs([]string{"-f", "/tmp/dump.json"})
+	if err != nil {
+		t.Fatalf("ParseLoadArgs() error = %v", err)
+	}
+	if cfg != (LoadConfig{File: "/tmp/dump.json"}) {
+		t.Fatalf("ParseLoadArgs() = %#v, want File /tmp/dump.json", cfg)
+	}
+
+	if _, err := ParseLoadArgs([]string{"--bogus"}); err == nil || !strings.Contains(err.Error(), `unknown argument "--bogus"`) {
+		t.Fatalf("ParseLoadArgs() error = %v, want unknown argument", err)
+	}
+}
+
+func TestDumpAndLoadRuntime(t *testing.T) {
+	src := SetupEmulatorWithClients(t,
+		WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL),
+		WithSetupDDLs([]string{"CREATE TABLE singers (singer_id bigint PRIMARY KEY, name varchar)"}),
+		WithSetupRawDMLs([]string{"INSERT INTO singers (singer_id, name) VALUES (1, 'Alice')"}),
+	)
+
+	path := filepath.Join(t.TempDir(), "dump.json")
+	dump, err := DumpRuntime(t.Context(), src.Emulator())
+	if err != nil {
+		t.Fatalf("DumpRuntime() error = %v", err)
+	}
+	if err := WriteDumpFile(path, dump); err != nil {
+		t.Fatal(err)
+	}
+	dump, err = ReadDumpFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Databases) != 1 || dump.Databases[0].Dialect != databasepb.DatabaseDialect_POSTGRESQL {
+		b, _ := json.Marshal(dump)
+		t.Fatalf("DumpRuntime() = %s, want one PostgreSQL database", b)
+	}
+
+	dst := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	if err := LoadRuntime(t.Context(), dst, dump); err != nil {
+		t.Fatalf("LoadRuntime() error = %v", err)
+	}
+	clients := SetupClients(t, dst, WithDatabaseID(dump.Databases[0].DatabaseID), DisableAutoConfig(),
+		WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL))
+
+	var name string
+	err = clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT name FROM singers WHERE singer_id = 1")).Do(func(r *spanner.Row) error {
+		return r.Column(0, &name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Alice" {
+		t.Fatalf("loaded name = %q, want Alice", name)
+	}
+}
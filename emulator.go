@@ -57,6 +57,9 @@ func (e *Emulator) Close() error {
 		return nil
 	}
 	return ensureCloseState(&e.closeState).close(func() error {
+		if e.opts != nil {
+			e.opts.schemaTemplates.close()
+		}
 		if e.container == nil {
 			return nil
 		}
@@ -0,0 +1,335 @@
+package spanemuboost
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"google.golang.org/api/option"
+	"google.golang.org/api/option/internaloption"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	emulatorBinaryEnv = "SPANEMUBOOST_EMULATOR_BINARY"
+	gatewayMainName   = "gateway_main"
+
+	emulatorBinaryStartupTimeout = 30 * time.Second
+	emulatorBinaryProbeTimeout   = time.Second
+	emulatorBinaryProbeInterval  = 20 * time.Millisecond
+	emulatorBinaryStopTimeout    = 5 * time.Second
+
+	// emulatorBinaryOutputLimit bounds the child output kept for startup
+	// error messages.
+	emulatorBinaryOutputLimit = 16 << 10
+)
+
+// WithEmulatorBinary configures the gateway_main binary run by
+// [BackendEmulatorBinary]. path is either the binary itself or the directory
+// containing it, such as an extracted release archive. The gateway starts the
+// emulator_main binary next to it.
+//
+// Without this option, SPANEMUBOOST_EMULATOR_BINARY is used, and then
+// gateway_main on PATH. Other backends ignore this option.
+func WithEmulatorBinary(path string) Option {
+	return func(opts *emulatorOptions) error {
+		if strings.TrimSpace(path) == "" {
+			return errors.New("WithEmulatorBinary: path must not be empty")
+		}
+		opts.emulatorBinary = path
+		return nil
+	}
+}
+
+// resolveEmulatorBinary returns the gateway_main path to run.
+func resolveEmulatorBinary(opts *emulatorOptions) (string, error) {
+	path := cmp.Or(strings.TrimSpace(opts.emulatorBinary), strings.TrimSpace(os.Getenv(emulatorBinaryEnv)))
+	if path == "" {
+		found, err := exec.LookPath(gatewayMainName)
+		if err != nil {
+			return "", fmt.Errorf("spanemuboost: BackendEmulatorBinary needs the emulator %s binary; use WithEmulatorBinary, set %s, or add it to PATH: %w", gatewayMainName, emulatorBinaryEnv, err)
+		}
+		return found, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("spanemuboost: emulator binary: %w", err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, gatewayMainName)
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("spanemuboost: emulator binary: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// emulatorBinaryArgs returns the gateway_main arguments for serving gRPC and
+// REST on loopback ports, followed by the emulator gateway flag options.
+func emulatorBinaryArgs(opts *emulatorOptions, grpcPort, httpPort int) []string {
+	args := []string{
+		"--hostname", "127.0.0.1",
+		"--grpc_port", strconv.Itoa(grpcPort),
+		"--http_port", strconv.Itoa(httpPort),
+	}
+	return append(args, opts.gatewayFlags...)
+}
+
+// freeLoopbackPort returns a loopback TCP port that was free when checked.
+// Another process may take it before the emulator binds it; startup then
+// fails with the emulator's own error.
+func freeLoopbackPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// emulatorBinaryRuntime runs the Cloud Spanner Emulator binaries as a child
+// process group.
+type emulatorBinaryRuntime struct {
+	process *emulatorProcess
+	opts    *emulatorOptions
+	uri     string
+
+	closeState closeState
+}
+
+func (*emulatorBinaryRuntime) spanemuboostRuntime() {}
+
+// URI returns the gRPC endpoint (host:port) of the emulator.
+func (r *emulatorBinaryRuntime) URI() string { return r.uri }
+
+// ClientOptions returns [option.ClientOption] values for connecting to the
+// emulator without authentication.
+func (r *emulatorBinaryRuntime) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint("passthrough:///" + r.uri),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		internaloption.SkipDialSettingsValidation(),
+	}
+}
+
+// Close kills the emulator process group.
+func (r *emulatorBinaryRuntime) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.closeState.close(func() error {
+		if r.opts != nil {
+			r.opts.schemaTemplates.close()
+		}
+		if r.process == nil {
+			return nil
+		}
+		return r.process.stop()
+	})
+}
+
+// ProjectID returns the project ID.
+func (r *emulatorBinaryRuntime) ProjectID() string { return r.opts.projectID }
+
+// InstanceID returns the instance ID.
+func (r *emulatorBinaryRuntime) InstanceID() string { return r.opts.instanceID }
+
+// DatabaseID returns the configured database ID.
+func (r *emulatorBinaryRuntime) DatabaseID() string { return r.opts.databaseID }
+
+// ProjectPath returns the project resource path.
+func (r *emulatorBinaryRuntime) ProjectPath() string { return r.opts.ProjectPath() }
+
+// InstancePath returns the instance resource path.
+func (r *emulatorBinaryRuntime) InstancePath() string { return r.opts.InstancePath() }
+
+// DatabasePath returns the database resource path.
+func (r *emulatorBinaryRuntime) DatabasePath() string { return r.opts.DatabasePath() }
+
+func (r *emulatorBinaryRuntime) inheritedOptions(options ...Option) (*emulatorOptions, error) {
+	base := inheritedRuntimeOptions(r.opts)
+	return applyOptionsWithBase(base, options...)
+}
+
+// runtimePlatform reports the host platform because the binaries run
+// natively.
+func (r *emulatorBinaryRuntime) runtimePlatform(context.Context) (string, error) {
+	return goruntime.GOOS + "/" + goruntime.GOARCH, nil
+}
+
+func runEmulatorBinary(ctx context.Context, options ...Option) (Runtime, error) {
+	opts, err := applyOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := startEmulatorBinary(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := bootstrap(ctx, opts, r.ClientOptions()...); err != nil {
+		return nil, errors.Join(err, r.Close())
+	}
+	return r, nil
+}
+
+func runEmulatorBinaryWithClients(ctx context.Context, options ...Option) (*RuntimeEnv, error) {
+	opts, err := applyOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := startEmulatorBinary(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := bootstrapAndCreateClientsWithOptions(ctx, r.URI(), opts, r.ClientOptions())
+	if err != nil {
+		return nil, errors.Join(err, r.Close())
+	}
+
+	disableSchemaTeardownUnlessForced(opts, clients)
+
+	return &RuntimeEnv{Clients: clients, runtime: r}, nil
+}
+
+func startEmulatorBinary(ctx context.Context, opts *emulatorOptions) (*emulatorBinaryRuntime, error) {
+	path, err := resolveEmulatorBinary(opts)
+	if err != nil {
+		return nil, err
+	}
+	grpcPort, err := freeLoopbackPort()
+	if err != nil {
+		return nil, fmt.Errorf("spanemuboost: pick emulator gRPC port: %w", err)
+	}
+	httpPort, err := freeLoopbackPort()
+	if err != nil {
+		return nil, fmt.Errorf("spanemuboost: pick emulator HTTP port: %w", err)
+	}
+
+	process, err := startEmulatorProcess(path, emulatorBinaryArgs(opts, grpcPort, httpPort)...)
+	if err != nil {
+		return nil, err
+	}
+	uri := net.JoinHostPort("127.0.0.1", strconv.Itoa(grpcPort))
+	if err := waitEmulatorBinaryReady(ctx, process, uri, opts.projectID); err != nil {
+		return nil, errors.Join(err, process.stop())
+	}
+
+	enableSchemaTemplates(opts)
+	return &emulatorBinaryRuntime{process: process, opts: opts, uri: uri}, nil
+}
+
+// waitEmulatorBinaryReady polls the instance admin API until the emulator
+// answers, the process exits, or the startup timeout expires.
+func waitEmulatorBinaryReady(ctx context.Context, process *emulatorProcess, uri, projectID string) error {
+	ctx, cancel := context.WithTimeout(ctx, emulatorBinaryStartupTimeout)
+	defer cancel()
+
+	conn, err := grpc.NewClient("passthrough:///"+uri, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("spanemuboost: connect to emulator binary: %w", err)
+	}
+	defer conn.Close()
+	client := instancepb.NewInstanceAdminClient(conn)
+
+	for {
+		probeCtx, probeCancel := context.WithTimeout(ctx, emulatorBinaryProbeTimeout)
+		err := probeInstanceAdmin(probeCtx, client, projectID)
+		probeCancel()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-process.exited:
+			return fmt.Errorf("spanemuboost: emulator binary exited before it was ready: %v\n%s", process.waitErr, process.output.String())
+		case <-ctx.Done():
+			return fmt.Errorf("spanemuboost: emulator binary was not ready: %w\n%s", context.Cause(ctx), process.output.String())
+		case <-time.After(emulatorBinaryProbeInterval):
+		}
+	}
+}
+
+// emulatorProcess is a child process started in its own process group so
+// that stopping it also stops emulator_main started by gateway_main.
+type emulatorProcess struct {
+	cmd    *exec.Cmd
+	output *tailBuffer
+
+	exited  chan struct{} // closed after waitErr is set
+	waitErr error
+}
+
+func startEmulatorProcess(path string, args ...string) (*emulatorProcess, error) {
+	output := &tailBuffer{limit: emulatorBinaryOutputLimit}
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("spanemuboost: start emulator binary: %w", err)
+	}
+
+	p := &emulatorProcess{cmd: cmd, output: output, exited: make(chan struct{})}
+	go func() {
+		p.waitErr = cmd.Wait()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// stop terminates the process group and waits up to emulatorBinaryStopTimeout
+// for the process to exit. Then it kills whatever is left in the group, such
+// as emulator_main after gateway_main crashed, and waits for the process. The
+// group is signaled even if the process has already exited.
+func (p *emulatorProcess) stop() error {
+	if err := terminateProcessGroup(p.cmd); err != nil {
+		return fmt.Errorf("spanemuboost: stop emulator binary: %w", err)
+	}
+	select {
+	case <-p.exited:
+	case <-time.After(emulatorBinaryStopTimeout):
+	}
+	if err := killProcessGroup(p.cmd); err != nil {
+		return fmt.Errorf("spanemuboost: kill emulator binary: %w", err)
+	}
+	<-p.exited
+	return nil
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package spanemuboost
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Process groups are unix-only; the emulator binaries are published for Linux.
+
+func setProcessGroup(*exec.Cmd) {}
+
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return killProcessGroup(cmd)
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
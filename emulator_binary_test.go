@@ -0,0 +1,171 @@
+//go:build unix
+
+package spanemuboost
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFakeGateway(t *testing.T, dir, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, gatewayMainName)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveEmulatorBinary(t *testing.T) {
+	dir := t.TempDir()
+	gateway := writeFakeGateway(t, dir, "exit 0")
+	t.Setenv("PATH", t.TempDir())
+
+	t.Setenv(emulatorBinaryEnv, "")
+	if _, err := resolveEmulatorBinary(&emulatorOptions{}); err == nil || !strings.Contains(err.Error(), "WithEmulatorBinary") {
+		t.Fatalf("resolveEmulatorBinary() without a binary error = %v, want a pointer to WithEmulatorBinary", err)
+	}
+
+	t.Setenv(emulatorBinaryEnv, gateway)
+	if got, err := resolveEmulatorBinary(&emulatorOptions{}); err != nil || got != gateway {
+		t.Fatalf("resolveEmulatorBinary() from %s = %q, %v; want %q", emulatorBinaryEnv, got, err, gateway)
+	}
+
+	opts, err := applyOptions(WithEmulatorBinary(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolveEmulatorBinary(opts); err != nil || got != gateway {
+		t.Fatalf("resolveEmulatorBinary() from a directory = %q, %v; want %q", got, err, gateway)
+	}
+
+	if _, err := applyOptions(WithEmulatorBinary(" ")); err == nil {
+		t.Fatal("WithEmulatorBinary(\" \") error = nil, want error")
+	}
+}
+
+func TestEmulatorBinaryArgs(t *testing.T) {
+	opts, err := applyOptions(WithMaxDatabasesPerInstance(200), DisableQueryNullFilteredIndexCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"--hostname", "127.0.0.1",
+		"--grpc_port", "9010",
+		"--http_port", "9020",
+		"--override_max_databases_per_instance=200",
+		"--disable_query_null_filtered_index_check",
+	}
+	if diff := cmp.Diff(want, emulatorBinaryArgs(opts, 9010, 9020)); diff != "" {
+		t.Fatalf("emulatorBinaryArgs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunEmulatorBinaryReportsEarlyExit(t *testing.T) {
+	gateway := writeFakeGateway(t, t.TempDir(), "echo 'cannot bind port' >&2\nexit 1")
+
+	_, err := Run(t.Context(), BackendEmulatorBinary, WithEmulatorBinary(gateway))
+	if err == nil || !strings.Contains(err.Error(), "exited before it was ready") || !strings.Contains(err.Error(), "cannot bind port") {
+		t.Fatalf("Run() error = %v, want an early exit with the binary's output", err)
+	}
+}
+
+func TestEmulatorProcessStopKillsProcessGroup(t *testing.T) {
+	// The fake gateway starts a child, like gateway_main starts emulator_main.
+	gateway := writeFakeGateway(t, t.TempDir(), "sleep 300 &\necho $!\nwait")
+	process, err := startEmulatorProcess(gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var child int
+	for deadline := time.Now().Add(5 * time.Second); child == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("fake gateway did not report its child; output: %q", process.output.String())
+		}
+		child, _ = strconv.Atoi(strings.TrimSpace(process.output.String()))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := process.stop(); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !processGone(child); {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d survived stop", child)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEmulatorProcessStopKillsOrphanedChild(t *testing.T) {
+	// The fake gateway exits and leaves its child running, like a crashed
+	// gateway_main leaves emulator_main. The child does not hold the output
+	// pipe, so that the gateway is reaped.
+	gateway := writeFakeGateway(t, t.TempDir(), "sleep 300 >/dev/null 2>&1 &\necho $!")
+	process, err := startEmulatorProcess(gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-process.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake gateway did not exit")
+	}
+	child, err := strconv.Atoi(strings.TrimSpace(process.output.String()))
+	if err != nil {
+		t.Fatalf("fake gateway did not report its child; output: %q", process.output.String())
+	}
+	if processGone(child) {
+		t.Fatalf("child process %d exited with the gateway", child)
+	}
+
+	if err := process.stop(); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !processGone(child); {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d survived stop", child)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// processGone reports whether pid no longer runs. An orphan that was killed
+// may stay a zombie until it is reaped by init.
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
+func TestSetupWithClientsEmulatorBinary(t *testing.T) {
+	if _, err := resolveEmulatorBinary(&emulatorOptions{}); err != nil {
+		t.Skip(err)
+	}
+
+	env := SetupWithClients(t, BackendEmulatorBinary,
+		WithSetupDDLs(inProcessTestDDLs),
+		WithSetupMutations([]*spanner.Mutation{
+			spanner.Insert("Singers", []string{"SingerId", "Name"}, []any{int64(1), "Alice"}),
+		}),
+	)
+	if diff := cmp.Diff([]string{"Alice"}, readSingerNames(t, env.Client)); diff != "" {
+		t.Fatalf("rows mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -59,6 +59,10 @@ func backendForRuntime(runtime Runtime) Backend {
 	switch r := runtime.(type) {
 	case *omniRuntime:
 		return BackendOmni
+	case *inProcessRuntime:
+		return BackendInProcess
+	case *providedRuntime:
+		return r.backend
 	case *AttachedRuntime:
 		return r.backend
 	default:
@@ -205,13 +209,20 @@ func SaveEndpoint(path string, endpoint Endpoint) error {
 		return fmt.Errorf("spanemuboost: marshal endpoint: %w", err)
 	}
 	data = append(data, '\n')
+	return writeFileAtomic(path, "endpoint", data)
+}
+
+// writeFileAtomic writes data with mode 0600 through a temporary file in the
+// same directory, so readers never observe a partially written file. kind
+// names the file in error messages.
+func writeFileAtomic(path, kind string, data []byte) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("spanemuboost: create endpoint directory %q: %w", dir, err)
+		return fmt.Errorf("spanemuboost: create %s directory %q: %w", kind, dir, err)
 	}
-	tmp, err := os.CreateTemp(dir, ".endpoint-*.json")
+	tmp, err := os.CreateTemp(dir, "."+kind+"-*.json")
 	if err != nil {
-		return fmt.Errorf("spanemuboost: create temp endpoint file in %q: %w", dir, err)
+		return fmt.Errorf("spanemuboost: create temp %s file in %q: %w", kind, dir, err)
 	}
 	tmpPath := tmp.Name()
 	cleanup := true
@@ -222,24 +233,24 @@ func SaveEndpoint(path string, endpoint Endpoint) error {
 	}()
 	if _, err := tmp.Write(data); err != nil {
 		_ = tmp.Close()
-		return fmt.Errorf("spanemuboost: write temp endpoint file %q: %w", tmpPath, err)
+		return fmt.Errorf("spanemuboost: write temp %s file %q: %w", kind, tmpPath, err)
 	}
 	if err := tmp.Chmod(0o600); err != nil {
 		_ = tmp.Close()
-		return fmt.Errorf("spanemuboost: chmod temp endpoint file %q: %w", tmpPath, err)
+		return fmt.Errorf("spanemuboost: chmod temp %s file %q: %w", kind, tmpPath, err)
 	}
 	if err := tmp.Close(); err != nil {
-		return fmt.Errorf("spanemuboost: close temp endpoint file %q: %w", tmpPath, err)
+		return fmt.Errorf("spanemuboost: close temp %s file %q: %w", kind, tmpPath, err)
 	}
 	if err := os.Rename(tmpPath, path); err != nil {
-		return fmt.Errorf("spanemuboost: write endpoint file %q: %w", path, err)
+		return fmt.Errorf("spanemuboost: write %s file %q: %w", kind, path, err)
 	}
 	cleanup = false
 	return nil
 }
 
 func (e Endpoint) validate() error {
-	if e.Backend != BackendEmulator && e.Backend != BackendOmni {
+	if _, registered := lookupBackendProvider(e.Backend); e.Backend != BackendEmulator && e.Backend != BackendOmni && e.Backend != BackendInProcess && !registered {
 		return fmt.Errorf("spanemuboost: endpoint backend %q is unsupported", e.Backend)
 	}
 	if strings.TrimSpace(e.URI) == "" {
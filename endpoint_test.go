@@ -120,6 +120,16 @@ func TestParseServeArgsOmniWithDefaultDatabase(t *testing.T) {
 	}
 }
 
+func TestParseServeArgsStateFile(t *testing.T) {
+	cfg, err := ParseServeArgs([]string{"emulator", "--endpoint-file", "/tmp/emulator.json", "--state-file", "/tmp/state.json"})
+	if err != nil {
+		t.Fatalf("ParseServeArgs() error = %v", err)
+	}
+	if cfg.StateFile != "/tmp/state.json" {
+		t.Fatalf("StateFile = %q, want /tmp/state.json", cfg.StateFile)
+	}
+}
+
 func TestLoadEndpointMissingEnvMentionsEmulatorURI(t *testing.T) {
 	t.Setenv(endpointFileEnv, "")
 	t.Setenv(omniURIEnv, "")
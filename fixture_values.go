@@ -0,0 +1,424 @@
+package spanemuboost
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const commitTimestampPlaceholder = "spanner.commit_timestamp()"
+
+// setupProtoFiles builds a registry from the setup file descriptor set.
+func (o *emulatorOptions) setupProtoFiles() (*protoregistry.Files, error) {
+	if len(o.setupFileDescriptorSet) == 0 {
+		return nil, fmt.Errorf("PROTO and ENUM columns require WithSetupFileDescriptorSet or WithSetupRawFileDescriptorSet")
+	}
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(o.setupFileDescriptorSet, &fds); err != nil {
+		return nil, fmt.Errorf("unmarshal setup file descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, fmt.Errorf("setup file descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// parseSpannerType parses an INFORMATION_SCHEMA.COLUMNS.SPANNER_TYPE value.
+// GoogleSQL names not built into Spanner are resolved as PROTO or ENUM types
+// through protos, which is only called when needed.
+func parseSpannerType(s string, postgreSQL bool, protos func() (*protoregistry.Files, error)) (*sppb.Type, error) {
+	if postgreSQL {
+		return parsePGSpannerType(s)
+	}
+	if elem, ok := strings.CutPrefix(s, "ARRAY<"); ok && strings.HasSuffix(elem, ">") {
+		elemType, err := parseSpannerType(strings.TrimSuffix(elem, ">"), false, protos)
+		if err != nil {
+			return nil, err
+		}
+		return &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: elemType}, nil
+	}
+	base, _, _ := strings.Cut(s, "(")
+	switch base {
+	case "BOOL":
+		return &sppb.Type{Code: sppb.TypeCode_BOOL}, nil
+	case "INT64":
+		return &sppb.Type{Code: sppb.TypeCode_INT64}, nil
+	case "FLOAT32":
+		return &sppb.Type{Code: sppb.TypeCode_FLOAT32}, nil
+	case "FLOAT64":
+		return &sppb.Type{Code: sppb.TypeCode_FLOAT64}, nil
+	case "NUMERIC":
+		return &sppb.Type{Code: sppb.TypeCode_NUMERIC}, nil
+	case "STRING":
+		return &sppb.Type{Code: sppb.TypeCode_STRING}, nil
+	case "BYTES":
+		return &sppb.Type{Code: sppb.TypeCode_BYTES}, nil
+	case "JSON":
+		return &sppb.Type{Code: sppb.TypeCode_JSON}, nil
+	case "DATE":
+		return &sppb.Type{Code: sppb.TypeCode_DATE}, nil
+	case "TIMESTAMP":
+		return &sppb.Type{Code: sppb.TypeCode_TIMESTAMP}, nil
+	case "UUID":
+		return &sppb.Type{Code: sppb.TypeCode_UUID}, nil
+	case "INTERVAL":
+		return &sppb.Type{Code: sppb.TypeCode_INTERVAL}, nil
+	}
+
+	files, err := protos()
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %w", s, err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(s))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported type %s: %w", s, err)
+	}
+	switch desc.(type) {
+	case protoreflect.MessageDescriptor:
+		return &sppb.Type{Code: sppb.TypeCode_PROTO, ProtoTypeFqn: s}, nil
+	case protoreflect.EnumDescriptor:
+		return &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: s}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s: not a message or enum", s)
+	}
+}
+
+func parsePGSpannerType(s string) (*sppb.Type, error) {
+	if elem, ok := strings.CutSuffix(s, "[]"); ok {
+		elemType, err := parsePGSpannerType(elem)
+		if err != nil {
+			return nil, err
+		}
+		return &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: elemType}, nil
+	}
+	base, _, _ := strings.Cut(s, "(")
+	switch strings.TrimSpace(base) {
+	case "boolean":
+		return &sppb.Type{Code: sppb.TypeCode_BOOL}, nil
+	case "bigint":
+		return &sppb.Type{Code: sppb.TypeCode_INT64}, nil
+	case "real":
+		return &sppb.Type{Code: sppb.TypeCode_FLOAT32}, nil
+	case "double precision":
+		return &sppb.Type{Code: sppb.TypeCode_FLOAT64}, nil
+	case "numeric":
+		return &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}, nil
+	case "character varying", "text":
+		return &sppb.Type{Code: sppb.TypeCode_STRING}, nil
+	case "bytea":
+		return &sppb.Type{Code: sppb.TypeCode_BYTES}, nil
+	case "jsonb":
+		return &sppb.Type{Code: sppb.TypeCode_JSON, TypeAnnotation: sppb.TypeAnnotationCode_PG_JSONB}, nil
+	case "date":
+		return &sppb.Type{Code: sppb.TypeCode_DATE}, nil
+	case "timestamp with time zone", "spanner.commit_timestamp":
+		return &sppb.Type{Code: sppb.TypeCode_TIMESTAMP}, nil
+	case "uuid":
+		return &sppb.Type{Code: sppb.TypeCode_UUID}, nil
+	case "interval":
+		return &sppb.Type{Code: sppb.TypeCode_INTERVAL}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", s)
+	}
+}
+
+// decodeCSVCell decodes the JSON text of ARRAY, JSON, and PROTO cells.
+// Other cells are returned as is.
+func decodeCSVCell(cell any, typ *sppb.Type) (any, error) {
+	s, ok := cell.(string)
+	if !ok {
+		return cell, nil
+	}
+	switch typ.GetCode() {
+	case sppb.TypeCode_ARRAY:
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var v []any
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("ARRAY cell must be a JSON array: %w", err)
+		}
+		return v, nil
+	case sppb.TypeCode_JSON, sppb.TypeCode_PROTO:
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("%s cell must be valid JSON", typ.GetCode())
+		}
+		return json.RawMessage(s), nil
+	default:
+		return s, nil
+	}
+}
+
+// fixtureValue converts a decoded fixture value to the protobuf encoding of typ.
+func fixtureValue(v any, typ *sppb.Type, protos func() (*protoregistry.Files, error)) (*structpb.Value, error) {
+	if v == nil {
+		return structpb.NewNullValue(), nil
+	}
+	switch typ.GetCode() {
+	case sppb.TypeCode_BOOL:
+		switch v := v.(type) {
+		case bool:
+			return structpb.NewBoolValue(v), nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BOOL %q", v)
+			}
+			return structpb.NewBoolValue(b), nil
+		}
+	case sppb.TypeCode_INT64:
+		n, err := fixtureInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		return structpb.NewStringValue(strconv.FormatInt(n, 10)), nil
+	case sppb.TypeCode_FLOAT32, sppb.TypeCode_FLOAT64:
+		f, err := fixtureFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case math.IsNaN(f):
+			return structpb.NewStringValue("NaN"), nil
+		case math.IsInf(f, 1):
+			return structpb.NewStringValue("Infinity"), nil
+		case math.IsInf(f, -1):
+			return structpb.NewStringValue("-Infinity"), nil
+		}
+		return structpb.NewNumberValue(f), nil
+	case sppb.TypeCode_NUMERIC:
+		s, ok := fixtureScalarString(v)
+		if !ok {
+			break
+		}
+		if typ.GetTypeAnnotation() == sppb.TypeAnnotationCode_PG_NUMERIC && strings.EqualFold(s, "NaN") {
+			return structpb.NewStringValue("NaN"), nil
+		}
+		if _, ok := new(big.Rat).SetString(s); !ok {
+			return nil, fmt.Errorf("invalid NUMERIC %q", s)
+		}
+		return structpb.NewStringValue(s), nil
+	case sppb.TypeCode_STRING, sppb.TypeCode_UUID, sppb.TypeCode_INTERVAL:
+		if s, ok := fixtureScalarString(v); ok {
+			return structpb.NewStringValue(s), nil
+		}
+	case sppb.TypeCode_BYTES:
+		if s, ok := v.(string); ok {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("BYTES must be base64: %w", err)
+			}
+			return structpb.NewStringValue(base64.StdEncoding.EncodeToString(b)), nil
+		}
+	case sppb.TypeCode_JSON:
+		if raw, ok := v.(json.RawMessage); ok {
+			return structpb.NewStringValue(string(raw)), nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return structpb.NewStringValue(string(b)), nil
+	case sppb.TypeCode_DATE:
+		switch v := v.(type) {
+		case time.Time:
+			return structpb.NewStringValue(civil.DateOf(v).String()), nil
+		case string:
+			d, err := civil.ParseDate(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DATE %q", v)
+			}
+			return structpb.NewStringValue(d.String()), nil
+		}
+	case sppb.TypeCode_TIMESTAMP:
+		switch v := v.(type) {
+		case time.Time:
+			return structpb.NewStringValue(v.UTC().Format(time.RFC3339Nano)), nil
+		case string:
+			if v == commitTimestampPlaceholder {
+				return structpb.NewStringValue(v), nil
+			}
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TIMESTAMP %q, want RFC 3339 or %s", v, commitTimestampPlaceholder)
+			}
+			return structpb.NewStringValue(t.UTC().Format(time.RFC3339Nano)), nil
+		}
+	case sppb.TypeCode_ARRAY:
+		elems, ok := v.([]any)
+		if !ok {
+			break
+		}
+		values := make([]*structpb.Value, len(elems))
+		for i, elem := range elems {
+			value, err := fixtureValue(elem, typ.GetArrayElementType(), protos)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			values[i] = value
+		}
+		return structpb.NewListValue(&structpb.ListValue{Values: values}), nil
+	case sppb.TypeCode_PROTO:
+		return fixtureProtoValue(v, typ.GetProtoTypeFqn(), protos)
+	case sppb.TypeCode_ENUM:
+		return fixtureEnumValue(v, typ.GetProtoTypeFqn(), protos)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ.GetCode())
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", v, typ.GetCode())
+}
+
+func fixtureInt64(v any) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("INT64 %d out of range", v)
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("invalid INT64 %v", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return fixtureInt64(string(v))
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid INT64 %q", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to INT64", v)
+}
+
+func fixtureFloat64(v any) (float64, error) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case json.Number:
+		return fixtureFloat64(string(v))
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid float %q", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to a float", v)
+}
+
+// fixtureScalarString formats scalar values for STRING-like and NUMERIC columns.
+func fixtureScalarString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return string(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+func fixtureProtoValue(v any, fqn string, protos func() (*protoregistry.Files, error)) (*structpb.Value, error) {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		if _, ok := v.(map[string]any); !ok {
+			return nil, fmt.Errorf("PROTO %s value must be a protojson object, got %T", fqn, v)
+		}
+		var err error
+		raw, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("PROTO %s: %w", fqn, err)
+		}
+	}
+	files, err := protos()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(fqn))
+	if err != nil {
+		return nil, fmt.Errorf("PROTO %s: %w", fqn, err)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("PROTO %s: not a message", fqn)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: dynamicResolver(files)}).Unmarshal(raw, msg); err != nil {
+		return nil, fmt.Errorf("PROTO %s: %w", fqn, err)
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("PROTO %s: %w", fqn, err)
+	}
+	return structpb.NewStringValue(base64.StdEncoding.EncodeToString(b)), nil
+}
+
+func fixtureEnumValue(v any, fqn string, protos func() (*protoregistry.Files, error)) (*structpb.Value, error) {
+	files, err := protos()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(fqn))
+	if err != nil {
+		return nil, fmt.Errorf("ENUM %s: %w", fqn, err)
+	}
+	ed, ok := desc.(protoreflect.EnumDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("ENUM %s: not an enum", fqn)
+	}
+	if s, ok := v.(string); ok {
+		if value := ed.Values().ByName(protoreflect.Name(s)); value != nil {
+			return structpb.NewStringValue(strconv.Itoa(int(value.Number()))), nil
+		}
+	}
+	n, err := fixtureInt64(v)
+	if err != nil || n < math.MinInt32 || n > math.MaxInt32 {
+		return nil, fmt.Errorf("ENUM %s: unknown value %v", fqn, v)
+	}
+	return structpb.NewStringValue(strconv.FormatInt(n, 10)), nil
+}
+
+// dynamicResolver resolves extensions and Any types from files for protojson.
+func dynamicResolver(files *protoregistry.Files) interface {
+	protoregistry.ExtensionTypeResolver
+	protoregistry.MessageTypeResolver
+} {
+	return dynamicpb.NewTypes(files)
+}
@@ -0,0 +1,241 @@
+package spanemuboost
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func fixtureTestFileDescriptorSet() *descriptorpb.FileDescriptorSet {
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("fixtures.proto"),
+				Syntax:  proto.String("proto3"),
+				Package: proto.String("examples.fixtures"),
+				EnumType: []*descriptorpb.EnumDescriptorProto{
+					{
+						Name: proto.String("Color"),
+						Value: []*descriptorpb.EnumValueDescriptorProto{
+							{Name: proto.String("COLOR_UNSPECIFIED"), Number: proto.Int32(0)},
+							{Name: proto.String("RED"), Number: proto.Int32(1)},
+						},
+					},
+				},
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Item"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name:     proto.String("name"),
+								JsonName: proto.String("name"),
+								Number:   proto.Int32(1),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							},
+							{
+								Name:     proto.String("color"),
+								JsonName: proto.String("color"),
+								Number:   proto.Int32(2),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+								TypeName: proto.String(".examples.fixtures.Color"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func fixtureTestProtos(t *testing.T) func() (*protoregistry.Files, error) {
+	t.Helper()
+	files, err := protodesc.NewFiles(fixtureTestFileDescriptorSet())
+	if err != nil {
+		t.Fatalf("protodesc.NewFiles: %v", err)
+	}
+	return func() (*protoregistry.Files, error) { return files, nil }
+}
+
+func TestParseSpannerType(t *testing.T) {
+	protos := fixtureTestProtos(t)
+	tests := []struct {
+		spannerType string
+		postgreSQL  bool
+		want        *sppb.Type
+	}{
+		{spannerType: "STRING(MAX)", want: &sppb.Type{Code: sppb.TypeCode_STRING}},
+		{spannerType: "BYTES(16)", want: &sppb.Type{Code: sppb.TypeCode_BYTES}},
+		{spannerType: "ARRAY<INT64>", want: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_INT64}}},
+		{spannerType: "examples.fixtures.Item", want: &sppb.Type{Code: sppb.TypeCode_PROTO, ProtoTypeFqn: "examples.fixtures.Item"}},
+		{
+			spannerType: "ARRAY<examples.fixtures.Color>",
+			want: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{
+				Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.fixtures.Color",
+			}},
+		},
+		{spannerType: "character varying(10)", postgreSQL: true, want: &sppb.Type{Code: sppb.TypeCode_STRING}},
+		{spannerType: "numeric", postgreSQL: true, want: &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}},
+		{
+			spannerType: "jsonb[]",
+			postgreSQL:  true,
+			want: &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{
+				Code: sppb.TypeCode_JSON, TypeAnnotation: sppb.TypeAnnotationCode_PG_JSONB,
+			}},
+		},
+		{spannerType: "spanner.commit_timestamp", postgreSQL: true, want: &sppb.Type{Code: sppb.TypeCode_TIMESTAMP}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spannerType, func(t *testing.T) {
+			got, err := parseSpannerType(tt.spannerType, tt.postgreSQL, protos)
+			if err != nil {
+				t.Fatalf("parseSpannerType() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Fatalf("parseSpannerType() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSpannerTypeRequiresDescriptorsForProtoTypes(t *testing.T) {
+	opts, err := applyOptions()
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	_, err = parseSpannerType("examples.fixtures.Item", false, opts.setupProtoFiles)
+	if err == nil || !strings.Contains(err.Error(), "require WithSetupFileDescriptorSet") {
+		t.Fatalf("parseSpannerType() error = %v, want descriptor set requirement", err)
+	}
+}
+
+func TestFixtureValue(t *testing.T) {
+	protos := fixtureTestProtos(t)
+	tests := []struct {
+		name string
+		v    any
+		typ  *sppb.Type
+		want *structpb.Value
+	}{
+		{name: "null", v: nil, typ: &sppb.Type{Code: sppb.TypeCode_INT64}, want: structpb.NewNullValue()},
+		{name: "int64 from json.Number", v: json.Number("9007199254740993"), typ: &sppb.Type{Code: sppb.TypeCode_INT64}, want: structpb.NewStringValue("9007199254740993")},
+		{name: "int64 from yaml int", v: 42, typ: &sppb.Type{Code: sppb.TypeCode_INT64}, want: structpb.NewStringValue("42")},
+		{name: "bool from csv", v: "true", typ: &sppb.Type{Code: sppb.TypeCode_BOOL}, want: structpb.NewBoolValue(true)},
+		{name: "float64 infinity", v: "-Inf", typ: &sppb.Type{Code: sppb.TypeCode_FLOAT64}, want: structpb.NewStringValue("-Infinity")},
+		{name: "numeric", v: "123.456000000", typ: &sppb.Type{Code: sppb.TypeCode_NUMERIC}, want: structpb.NewStringValue("123.456000000")},
+		{name: "pg numeric NaN", v: "NaN", typ: &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}, want: structpb.NewStringValue("NaN")},
+		{name: "bytes", v: "aGVsbG8=", typ: &sppb.Type{Code: sppb.TypeCode_BYTES}, want: structpb.NewStringValue("aGVsbG8=")},
+		{name: "json object", v: map[string]any{"a": json.Number("1")}, typ: &sppb.Type{Code: sppb.TypeCode_JSON}, want: structpb.NewStringValue(`{"a":1}`)},
+		{name: "json from csv", v: json.RawMessage(`{"a": 1}`), typ: &sppb.Type{Code: sppb.TypeCode_JSON}, want: structpb.NewStringValue(`{"a": 1}`)},
+		{name: "date", v: "2024-02-29", typ: &sppb.Type{Code: sppb.TypeCode_DATE}, want: structpb.NewStringValue("2024-02-29")},
+		{name: "timestamp", v: "2024-01-02T03:04:05+09:00", typ: &sppb.Type{Code: sppb.TypeCode_TIMESTAMP}, want: structpb.NewStringValue("2024-01-01T18:04:05Z")},
+		{name: "timestamp from time.Time", v: time.Date(2024, 1, 1, 0, 0, 0, 5, time.UTC), typ: &sppb.Type{Code: sppb.TypeCode_TIMESTAMP}, want: structpb.NewStringValue("2024-01-01T00:00:00.000000005Z")},
+		{name: "commit timestamp", v: "spanner.commit_timestamp()", typ: &sppb.Type{Code: sppb.TypeCode_TIMESTAMP}, want: structpb.NewStringValue("spanner.commit_timestamp()")},
+		{
+			name: "array with null",
+			v:    []any{json.Number("1"), nil},
+			typ:  &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_INT64}},
+			want: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("1"), structpb.NewNullValue()}}),
+		},
+		{name: "enum by name", v: "RED", typ: &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.fixtures.Color"}, want: structpb.NewStringValue("1")},
+		{name: "enum by number", v: json.Number("1"), typ: &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.fixtures.Color"}, want: structpb.NewStringValue("1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fixtureValue(tt.v, tt.typ, protos)
+			if err != nil {
+				t.Fatalf("fixtureValue() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Fatalf("fixtureValue() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFixtureValueProto(t *testing.T) {
+	protos := fixtureTestProtos(t)
+	typ := &sppb.Type{Code: sppb.TypeCode_PROTO, ProtoTypeFqn: "examples.fixtures.Item"}
+
+	files, err := protos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := files.FindDescriptorByName("examples.fixtures.Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := desc.(protoreflect.MessageDescriptor)
+	want := dynamicpb.NewMessage(md)
+	want.Set(md.Fields().ByName("name"), protoreflect.ValueOfString("a"))
+	want.Set(md.Fields().ByName("color"), protoreflect.ValueOfEnum(1))
+
+	for _, v := range []any{
+		map[string]any{"name": "a", "color": "RED"},
+		json.RawMessage(`{"name": "a", "color": 1}`),
+	} {
+		value, err := fixtureValue(v, typ, protos)
+		if err != nil {
+			t.Fatalf("fixtureValue(%v) error = %v", v, err)
+		}
+		b, err := base64.StdEncoding.DecodeString(value.GetStringValue())
+		if err != nil {
+			t.Fatalf("fixtureValue(%v) = %q, want base64: %v", v, value.GetStringValue(), err)
+		}
+		got := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(b, got); err != nil {
+			t.Fatalf("proto.Unmarshal: %v", err)
+		}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Fatalf("fixtureValue(%v) mismatch (-want +got):\n%s", v, diff)
+		}
+	}
+}
+
+func TestFixtureValueReportsInvalidValues(t *testing.T) {
+	protos := fixtureTestProtos(t)
+	tests := []struct {
+		name string
+		v    any
+		typ  *sppb.Type
+		want string
+	}{
+		{name: "int64", v: "1.5", typ: &sppb.Type{Code: sppb.TypeCode_INT64}, want: `invalid INT64 "1.5"`},
+		{name: "numeric", v: "abc", typ: &sppb.Type{Code: sppb.TypeCode_NUMERIC}, want: `invalid NUMERIC "abc"`},
+		{name: "bytes", v: "not base64!", typ: &sppb.Type{Code: sppb.TypeCode_BYTES}, want: "BYTES must be base64"},
+		{name: "date", v: "2024-13-01", typ: &sppb.Type{Code: sppb.TypeCode_DATE}, want: `invalid DATE "2024-13-01"`},
+		{name: "string from object", v: map[string]any{}, typ: &sppb.Type{Code: sppb.TypeCode_STRING}, want: "cannot convert map[string]interface {} to STRING"},
+		{name: "enum", v: "BLUE", typ: &sppb.Type{Code: sppb.TypeCode_ENUM, ProtoTypeFqn: "examples.fixtures.Color"}, want: "unknown value BLUE"},
+		{
+			name: "array element",
+			v:    []any{"x"},
+			typ:  &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_BOOL}},
+			want: `element 0: invalid BOOL "x"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fixtureValue(tt.v, tt.typ, protos)
+			if err == nil {
+				t.Fatal("fixtureValue() error = nil, want non-nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("fixtureValue() error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package spanemuboost
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"gopkg.in/yaml.v3"
+)
+
+// fixture is the parsed content of one fixture file. Rows are converted to
+// Spanner values at bootstrap time, once the table schema is known.
+type fixture struct {
+	file  string
+	table string
+	rows  []fixtureRow
+	// csv reports whether cells are raw CSV text, in which ARRAY, JSON, and
+	// PROTO values are encoded as JSON.
+	csv bool
+}
+
+type fixtureRow struct {
+	columns []string
+	values  []any
+}
+
+// WithSetupFixtures loads table fixtures from files in fsys and inserts them
+// as mutations after the database schema is set up.
+//
+// Files are selected like [WithSetupDDLFiles]. Each file holds rows of the
+// table named by its base name without the extension, e.g. Singers.csv or
+// myschema.Albums.yaml. Supported formats are:
+//
+//   - .csv: the header row names the columns. An empty cell is NULL. ARRAY,
+//     JSON, and PROTO cells are written as JSON text.
+//   - .json: an array of objects keyed by column name.
+//   - .yaml, .yml: a sequence of mappings keyed by column name.
+//
+// Column types are read from INFORMATION_SCHEMA and values are converted
+// accordingly. BYTES values are base64 strings, NUMERIC values should be
+// strings to keep their precision, TIMESTAMP values are RFC 3339 strings or
+// "spanner.commit_timestamp()", PROTO values are protojson objects, and ENUM
+// values are names or numbers. PROTO and ENUM columns require
+// [WithSetupFileDescriptorSet] or [WithSetupRawFileDescriptorSet].
+//
+// Tables are written parent-before-child for interleaved tables and
+// referenced-before-referencing for foreign keys. Fixtures are applied before
+// [WithSetupMutations] and setup DMLs.
+//
+// Calling this multiple times appends to the previous value.
+func WithSetupFixtures(fsys fs.FS, patterns ...string) Option {
+	return func(opts *emulatorOptions) error {
+		files, err := readSetupFiles(fsys, patterns)
+		if err != nil {
+			return fmt.Errorf("WithSetupFixtures: %w", err)
+		}
+		for _, file := range files {
+			f, err := parseFixture(file)
+			if err != nil {
+				return fmt.Errorf("WithSetupFixtures: %w", err)
+			}
+			opts.setupFixtures = append(opts.setupFixtures, f)
+		}
+		return nil
+	}
+}
+
+func parseFixture(file setupFile) (fixture, error) {
+	base := path.Base(file.name)
+	ext := path.Ext(base)
+	f := fixture{file: file.name, table: strings.TrimSuffix(base, ext)}
+	if f.table == "" {
+		return fixture{}, fmt.Errorf("%s: cannot derive a table name", file.name)
+	}
+
+	var err error
+	switch strings.ToLower(ext) {
+	case ".csv":
+		f.csv = true
+		f.rows, err = parseCSVFixture(file.content)
+	case ".json":
+		f.rows, err = parseJSONFixture(file.content)
+	case ".yaml", ".yml":
+		f.rows, err = parseYAMLFixture(file.content)
+	default:
+		return fixture{}, fmt.Errorf("%s: unsupported fixture format %q, want .csv, .json, .yaml, or .yml", file.name, ext)
+	}
+	if err != nil {
+		return fixture{}, fmt.Errorf("%s: %w", file.name, err)
+	}
+	return f, nil
+}
+
+func parseCSVFixture(content string) ([]fixtureRow, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		if header[i] == "" {
+			return nil, fmt.Errorf("column %d in the header is empty", i+1)
+		}
+	}
+
+	var rows []fixtureRow
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(record))
+		for i, cell := range record {
+			if cell != "" {
+				values[i] = cell
+			}
+		}
+		rows = append(rows, fixtureRow{columns: header, values: values})
+	}
+}
+
+func parseJSONFixture(content string) ([]fixtureRow, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var records []map[string]any
+	if err := dec.Decode(&records); err != nil {
+		return nil, err
+	}
+	return mapFixtureRows(records), nil
+}
+
+func parseYAMLFixture(content string) ([]fixtureRow, error) {
+	var records []map[string]any
+	if err := yaml.NewDecoder(strings.NewReader(content)).Decode(&records); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return mapFixtureRows(records), nil
+}
+
+func mapFixtureRows(records []map[string]any) []fixtureRow {
+	rows := make([]fixtureRow, 0, len(records))
+	for _, record := range records {
+		columns := slices.Sorted(maps.Keys(record))
+		values := make([]any, len(columns))
+		for i, column := range columns {
+			values[i] = record[column]
+		}
+		rows = append(rows, fixtureRow{columns: columns, values: values})
+	}
+	return rows
+}
+
+// fixtureTable is the part of a table schema needed to apply fixtures.
+type fixtureTable struct {
+	schema, name string
+	columns      map[string]string // column name -> SPANNER_TYPE
+	generated    map[string]bool   // generated column names
+	deps         []string          // qualified names of parent and referenced tables
+}
+
+func (t *fixtureTable) qualifiedName() string {
+	return qualifiedTableName(t.schema, t.name)
+}
+
+func qualifiedTableName(schema, name string) string {
+	if schema == "" || schema == "public" {
+		return name
+	}
+	return schema + "." + name
+}
+
+// fixtureSchema holds the user tables of a database keyed by qualified name.
+type fixtureSchema struct {
+	tables     map[string]*fixtureTable
+	postgreSQL bool
+}
+
+const fixtureSchemaFilter = `table_schema NOT IN ('INFORMATION_SCHEMA', 'SPANNER_SYS', 'information_schema', 'spanner_sys', 'pg_catalog')`
+
+// loadFixtureSchema reads table, column, interleaving, and foreign key metadata.
+// The queries are valid in both dialects because INFORMATION_SCHEMA identifiers
+// are case-insensitive in GoogleSQL and lower case in PostgreSQL.
+func loadFixtureSchema(ctx context.Context, client *spanner.Client, dialect databasepb.DatabaseDialect) (*fixtureSchema, error) {
+	schema := &fixtureSchema{
+		tables:     make(map[string]*fixtureTable),
+		postgreSQL: dialect == databasepb.DatabaseDialect_POSTGRESQL,
+	}
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	err := queryStrings(ctx, txn, `SELECT table_schema, table_name, parent_table_name FROM information_schema.tables
+WHERE table_type = 'BASE TABLE' AND `+fixtureSchemaFilter, func(row []string) {
+		t := &fixtureTable{schema: row[0], name: row[1], columns: make(map[string]string), generated: make(map[string]bool)}
+		if row[2] != "" {
+			t.deps = append(t.deps, qualifiedTableName(row[0], row[2]))
+		}
+		schema.tables[t.qualifiedName()] = t
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read tables: %w", err)
+	}
+
+	err = queryStrings(ctx, txn, `SELECT table_schema, table_name, column_name, spanner_type, is_generated FROM information_schema.columns
+WHERE `+fixtureSchemaFilter, func(row []string) {
+		if t, ok := schema.tables[qualifiedTableName(row[0], row[1])]; ok {
+			t.columns[row[2]] = row[3]
+			if row[4] == "ALWAYS" {
+				t.generated[row[2]] = true
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read columns: %w", err)
+	}
+
+	err = queryStrings(ctx, txn, `SELECT tc.table_schema, tc.table_name, uc.table_schema, uc.table_name
+FROM information_schema.table_constraints AS tc
+JOIN information_schema.referential_constraints AS rc
+  ON rc.constraint_schema = tc.constraint_schema AND rc.constraint_name = tc.constraint_name
+JOIN information_schema.table_constraints AS uc
+  ON uc.constraint_schema = rc.unique_constraint_schema AND uc.constraint_name = rc.unique_constraint_name
+WHERE tc.constraint_type = 'FOREIGN KEY'`, func(row []string) {
+		if t, ok := schema.tables[qualifiedTableName(row[0], row[1])]; ok {
+			t.deps = append(t.deps, qualifiedTableName(row[2], row[3]))
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read foreign keys: %w", err)
+	}
+	return schema, nil
+}
+
+// queryStrings runs sql and calls f with each row read as strings, mapping NULL to "".
+func queryStrings(ctx context.Context, txn *spanner.ReadOnlyTransaction, sql string, f func(row []string)) error {
+	return txn.Query(ctx, spanner.NewStatement(sql)).Do(func(r *spanner.Row) error {
+		row := make([]string, r.Size())
+		for i := range row {
+			var v spanner.NullString
+			if err := r.Column(i, &v); err != nil {
+				return err
+			}
+			row[i] = v.StringVal
+		}
+		f(row)
+		return nil
+	})
+}
+
+// lookupTable resolves a fixture table name, optionally schema-qualified.
+// An exact match wins; otherwise a unique case-insensitive match is accepted.
+func (s *fixtureSchema) lookupTable(name string) (*fixtureTable, error) {
+	if t, ok := s.tables[name]; ok {
+		return t, nil
+	}
+	var found *fixtureTable
+	for qualified, t := range s.tables {
+		if strings.EqualFold(qualified, name) {
+			if found != nil {
+				return nil, fmt.Errorf("table %q is ambiguous", name)
+			}
+			found = t
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("table %q not found", name)
+	}
+	return found, nil
+}
+
+// lookupColumn resolves a fixture column name like lookupTable.
+func (t *fixtureTable) lookupColumn(name string) (string, string, error) {
+	if typ, ok := t.columns[name]; ok {
+		return name, typ, nil
+	}
+	var foundName, foundType string
+	for column, typ := range t.columns {
+		if strings.EqualFold(column, name) {
+			if foundName != "" {
+				return "", "", fmt.Errorf("column %q is ambiguous", name)
+			}
+			foundName, foundType = column, typ
+		}
+	}
+	if foundName == "" {
+		return "", "", fmt.Errorf("column %q not found in table %s", name, t.qualifiedName())
+	}
+	return foundName, foundType, nil
+}
+
+// orderFixtures orders fixtures so that parent and referenced tables come
+// before the tables that depend on them. Otherwise the original order is kept,
+// and a dependency cycle is broken at the fixture reached first.
+func orderFixtures(fixtures []fixture, tables []*fixtureTable) []int {
+	byTable := make(map[string][]int)
+	for i, t := range tables {
+		byTable[t.qualifiedName()] = append(byTable[t.qualifiedName()], i)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(fixtures))
+	order := make([]int, 0, len(fixtures))
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != unvisited {
+			return
+		}
+		state[i] = visiting
+		for _, dep := range tables[i].deps {
+			if dep == tables[i].qualifiedName() {
+				continue
+			}
+			for _, j := range byTable[dep] {
+				visit(j)
+			}
+		}
+		state[i] = done
+		order = append(order, i)
+	}
+	for i := range fixtures {
+		visit(i)
+	}
+	return order
+}
+
+// fixtureInsert is one fixture row converted to Spanner values.
+type fixtureInsert struct {
+	table   string
+	columns []string
+	values  []any
+}
+
+// convertFixtures converts fixture rows to inserts in dependency order.
+func convertFixtures(fixtures []fixture, schema *fixtureSchema, protos func() (*protoregistry.Files, error)) ([]fixtureInsert, error) {
+	tables := make([]*fixtureTable, len(fixtures))
+	for i, f := range fixtures {
+		t, err := schema.lookupTable(f.table)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.file, err)
+		}
+		tables[i] = t
+	}
+
+	var inserts []fixtureInsert
+	types := make(map[string]*sppb.Type)
+	for _, i := range orderFixtures(fixtures, tables) {
+		f, t := fixtures[i], tables[i]
+		for rowIndex, row := range f.rows {
+			columns := make([]string, len(row.columns))
+			values := make([]any, len(row.columns))
+			for j, name := range row.columns {
+				column, spannerType, err := t.lookupColumn(name)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", f.file, err)
+				}
+				typ, ok := types[spannerType]
+				if !ok {
+					typ, err = parseSpannerType(spannerType, schema.postgreSQL, protos)
+					if err != nil {
+						return nil, fmt.Errorf("%s: column %s: %w", f.file, column, err)
+					}
+					types[spannerType] = typ
+				}
+				v := row.values[j]
+				if f.csv {
+					v, err = decodeCSVCell(v, typ)
+					if err != nil {
+						return nil, fmt.Errorf("%s: row %d: column %s: %w", f.file, rowIndex+1, column, err)
+					}
+				}
+				value, err := fixtureValue(v, typ, protos)
+				if err != nil {
+					return nil, fmt.Errorf("%s: row %d: column %s: %w", f.file, rowIndex+1, column, err)
+				}
+				columns[j] = column
+				values[j] = spanner.GenericColumnValue{Type: typ, Value: value}
+			}
+			inserts = append(inserts, fixtureInsert{table: t.qualifiedName(), columns: columns, values: values})
+		}
+	}
+	return inserts, nil
+}
+
+// applyFixtures inserts the setup fixtures in chunked transactions.
+func applyFixtures(ctx context.Context, opts *emulatorOptions, client *spanner.Client) error {
+	schema, err := loadFixtureSchema(ctx, client, opts.databaseDialect)
+	if err != nil {
+		return fmt.Errorf("failed to read schema for fixtures: %w", err)
+	}
+	inserts, err := convertFixtures(opts.setupFixtures, schema, opts.setupProtoFiles)
+	if err != nil {
+		return fmt.Errorf("failed to convert fixtures: %w", err)
+	}
+	mutations := make([]*spanner.Mutation, len(inserts))
+	for i, insert := range inserts {
+		mutations[i] = spanner.Insert(insert.table, insert.columns, insert.values)
+	}
+	return applyMutationChunks(ctx, client, "fixture rows", mutations, opts.setupBatchSizeOrDefault(), nil)
+}
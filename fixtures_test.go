@@ -0,0 +1,242 @@
+package spanemuboost
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestWithSetupFixturesParsesFormats(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/Singers.csv":    {Data: []byte("SingerId,Name,Tags\n1,Alice,\"[\"\"a\"\"]\"\n2,,\n")},
+		"fixtures/Albums.json":    {Data: []byte(`[{"SingerId": 1, "AlbumId": 10, "Title": "First"}]`)},
+		"fixtures/sch.Songs.yaml": {Data: []byte("- SingerId: 1\n  AlbumId: 10\n  Released: 2024-01-01\n")},
+	}
+
+	opts, err := applyOptions(WithSetupFixtures(fsys, "fixtures/*"))
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+
+	want := []fixture{
+		{
+			file:  "fixtures/Albums.json",
+			table: "Albums",
+			rows: []fixtureRow{
+				{columns: []string{"AlbumId", "SingerId", "Title"}, values: []any{json.Number("10"), json.Number("1"), "First"}},
+			},
+		},
+		{
+			file:  "fixtures/Singers.csv",
+			table: "Singers",
+			csv:   true,
+			rows: []fixtureRow{
+				{columns: []string{"SingerId", "Name", "Tags"}, values: []any{"1", "Alice", `["a"]`}},
+				{columns: []string{"SingerId", "Name", "Tags"}, values: []any{"2", nil, nil}},
+			},
+		},
+		{
+			file:  "fixtures/sch.Songs.yaml",
+			table: "sch.Songs",
+			rows: []fixtureRow{
+				{columns: []string{"AlbumId", "Released", "SingerId"}, values: []any{10, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, opts.setupFixtures, cmp.AllowUnexported(fixture{}, fixtureRow{})); diff != "" {
+		t.Fatalf("setupFixtures mismatch (-want +got):\n%s", diff)
+	}
+	if !opts.hasSetupDataWork() {
+		t.Fatal("hasSetupDataWork() = false, want true")
+	}
+}
+
+func TestWithSetupFixturesReportsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "unsupported format",
+			fsys: fstest.MapFS{"Singers.txt": {Data: []byte("")}},
+			want: `Singers.txt: unsupported fixture format ".txt"`,
+		},
+		{
+			name: "malformed json",
+			fsys: fstest.MapFS{"Singers.json": {Data: []byte(`{"SingerId": 1}`)}},
+			want: "Singers.json: json: cannot unmarshal object",
+		},
+		{
+			name: "ragged csv",
+			fsys: fstest.MapFS{"Singers.csv": {Data: []byte("SingerId,Name\n1\n")}},
+			want: "Singers.csv: record on line 2: wrong number of fields",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyOptions(WithSetupFixtures(tt.fsys, "*"))
+			if err == nil {
+				t.Fatal("applyOptions() error = nil, want non-nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("applyOptions() error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func testFixtureSchema() *fixtureSchema {
+	return &fixtureSchema{tables: map[string]*fixtureTable{
+		"Singers": {name: "Singers", columns: map[string]string{
+			"SingerId": "INT64", "Name": "STRING(MAX)",
+		}},
+		"Albums": {name: "Albums", deps: []string{"Singers"}, columns: map[string]string{
+			"SingerId": "INT64", "AlbumId": "INT64",
+		}},
+		"Reviews": {name: "Reviews", deps: []string{"Albums", "Reviews"}, columns: map[string]string{
+			"ReviewId": "INT64",
+		}},
+		"sch.Labels": {schema: "sch", name: "Labels", columns: map[string]string{
+			"LabelId": "INT64",
+		}},
+	}}
+}
+
+func TestConvertFixturesOrdersDependenciesFirst(t *testing.T) {
+	fixtures := []fixture{
+		{file: "Reviews.json", table: "Reviews", rows: []fixtureRow{{columns: []string{"ReviewId"}, values: []any{1}}}},
+		{file: "Albums.csv", table: "albums", csv: true, rows: []fixtureRow{{columns: []string{"singerid", "AlbumId"}, values: []any{"1", "10"}}}},
+		{file: "Labels.json", table: "sch.Labels", rows: []fixtureRow{{columns: []string{"LabelId"}, values: []any{1}}}},
+		{file: "Singers.csv", table: "Singers", csv: true, rows: []fixtureRow{{columns: []string{"SingerId", "Name"}, values: []any{"1", nil}}}},
+	}
+
+	inserts, err := convertFixtures(fixtures, testFixtureSchema(), nil)
+	if err != nil {
+		t.Fatalf("convertFixtures() error = %v", err)
+	}
+
+	want := []fixtureInsert{
+		{table: "Singers", columns: []string{"SingerId", "Name"}, values: []any{
+			genericValue(t, int64(1)), genericValue(t, spanner.NullString{}),
+		}},
+		{table: "Albums", columns: []string{"SingerId", "AlbumId"}, values: []any{
+			genericValue(t, int64(1)), genericValue(t, int64(10)),
+		}},
+		{table: "Reviews", columns: []string{"ReviewId"}, values: []any{genericValue(t, int64(1))}},
+		{table: "sch.Labels", columns: []string{"LabelId"}, values: []any{genericValue(t, int64(1))}},
+	}
+	if diff := cmp.Diff(want, inserts, cmp.AllowUnexported(fixtureInsert{}), protocmp.Transform()); diff != "" {
+		t.Fatalf("convertFixtures() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvertFixturesReportsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture fixture
+		want    string
+	}{
+		{
+			name:    "unknown table",
+			fixture: fixture{file: "Missing.csv", table: "Missing"},
+			want:    `Missing.csv: table "Missing" not found`,
+		},
+		{
+			name:    "unknown column",
+			fixture: fixture{file: "Singers.json", table: "Singers", rows: []fixtureRow{{columns: []string{"Nope"}, values: []any{1}}}},
+			want:    `Singers.json: column "Nope" not found in table Singers`,
+		},
+		{
+			name:    "invalid value",
+			fixture: fixture{file: "Singers.csv", table: "Singers", csv: true, rows: []fixtureRow{{columns: []string{"SingerId"}, values: []any{"x"}}}},
+			want:    `Singers.csv: row 1: column SingerId: invalid INT64 "x"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := convertFixtures([]fixture{tt.fixture}, testFixtureSchema(), nil)
+			if err == nil {
+				t.Fatal("convertFixtures() error = nil, want non-nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("convertFixtures() error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderFixturesBreaksCycles(t *testing.T) {
+	tables := []*fixtureTable{
+		{name: "A", deps: []string{"B"}},
+		{name: "B", deps: []string{"A"}},
+		{name: "C"},
+	}
+	got := orderFixtures(make([]fixture, len(tables)), tables)
+	if diff := cmp.Diff([]int{1, 0, 2}, got); diff != "" {
+		t.Fatalf("orderFixtures() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// genericValue encodes v the way convertFixtures does, for comparison.
+func genericValue(t *testing.T, v any) spanner.GenericColumnValue {
+	t.Helper()
+	row, err := spanner.NewRow([]string{"v"}, []any{v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gcv spanner.GenericColumnValue
+	if err := row.Column(0, &gcv); err != nil {
+		t.Fatal(err)
+	}
+	return gcv
+}
+
+func TestWithSetupFixtures(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/Albums.yaml": {Data: []byte(`- SingerId: 1
+  AlbumId: 10
+  Title: First
+  Meta: {tracks: 12}
+  Cover: aGVsbG8=
+  Price: "12.50"
+`)},
+		"fixtures/Singers.csv": {Data: []byte("SingerId,Name,Tags\n1,Alice,\"[\"\"a\"\",null]\"\n2,,\n")},
+	}
+
+	env := SetupEmulatorWithClients(t,
+		WithSetupDDLs([]string{
+			"CREATE TABLE Singers (SingerId INT64, Name STRING(MAX), Tags ARRAY<STRING(MAX)>) PRIMARY KEY (SingerId)",
+			`CREATE TABLE Albums (SingerId INT64, AlbumId INT64, Title STRING(MAX), Meta JSON, Cover BYTES(MAX), Price NUMERIC)
+PRIMARY KEY (SingerId, AlbumId), INTERLEAVE IN PARENT Singers`,
+		}),
+		WithSetupFixtures(fsys, "fixtures/*"),
+		WithSetupRawDMLs([]string{"UPDATE Singers SET Name = 'Bob' WHERE SingerId = 2"}),
+	)
+
+	type row struct {
+		Name  string  `spanner:"Name"`
+		Title *string `spanner:"Title"`
+		Meta  *string `spanner:"Meta"`
+	}
+	var got []*row
+	err := spanner.SelectAll(env.Client.Single().Query(t.Context(), spanner.NewStatement(
+		`SELECT s.Name, a.Title, TO_JSON_STRING(a.Meta) AS Meta
+FROM Singers AS s LEFT JOIN Albums AS a USING (SingerId) ORDER BY s.SingerId`)), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*row{
+		{Name: "Alice", Title: ptrOf("First"), Meta: ptrOf(`{"tracks":12}`)},
+		{Name: "Bob"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
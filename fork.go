@@ -0,0 +1,125 @@
+package spanemuboost
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+// WithForkData makes [Clients.Fork] and [ForkClients] copy the rows of the
+// source database into the new database. Without it, only the schema is
+// copied. The rows are read at a single timestamp, as [Clients.Snapshot] does.
+func WithForkData() Option {
+	return func(opts *emulatorOptions) error {
+		opts.forkData = true
+		return nil
+	}
+}
+
+// Fork creates a new database on the same runtime with the schema of the
+// database of c: its DDL as returned by GetDatabaseDdl, its proto bundle
+// descriptors, and its dialect. Use [WithForkData] to copy the rows too.
+//
+// The new database gets a random ID unless [WithDatabaseID] is given. Setup
+// DDLs, data, and steps in options are applied on top of the copied schema and
+// rows. Unlike databases opened with [OpenClients], the forked database is
+// dropped when the returned clients are closed; use [SkipSchemaTeardown] to
+// keep it. Call [Clients.Close] to close the clients.
+func (c *Clients) Fork(ctx context.Context, options ...Option) (*Clients, error) {
+	source, err := readDatabaseSchema(ctx, c.DatabaseClient, c.DatabasePath())
+	if err != nil {
+		return nil, err
+	}
+
+	schema := c.schemaOrDefault()
+	opts := &emulatorOptions{
+		projectID:              c.ProjectID,
+		instanceID:             c.InstanceID,
+		databaseDialect:        source.dialect,
+		disableCreateInstance:  true,
+		schemaTeardown:         ptrOf(true),
+		clientOptionsForClient: schema.clientOptionsForClient,
+		inProcess:              c.inProcess,
+	}
+	if schema.clientConfig != nil {
+		config := *schema.clientConfig
+		opts.clientConfig = &config
+	}
+	for _, opt := range options {
+		if err := opt(opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.databaseID == "" {
+		opts.randomDatabaseID = true
+	}
+	if opts, err = finalizeOptions(opts); err != nil {
+		return nil, err
+	}
+
+	source.prependTo(opts)
+	if opts.forkData {
+		if err := c.requireInformationSchema("WithForkData"); err != nil {
+			return nil, err
+		}
+		snap, err := snapshotDatabase(ctx, c.Client, source.dialect, 0)
+		if err != nil {
+			return nil, err
+		}
+		if opts.copiedRows, err = snapshotMutations(snap); err != nil {
+			return nil, err
+		}
+	}
+
+	return bootstrapAndCreateClientsWithOptions(ctx, c.uri, opts, c.clientOpts)
+}
+
+// ForkClients is the test helper form of [Clients.Fork]. It registers cleanup
+// via [testing.TB.Cleanup], which drops the forked database, and calls
+// [testing.TB.Fatal] on error.
+func ForkClients(tb testing.TB, source *Clients, options ...Option) *Clients {
+	tb.Helper()
+	return setupWithCleanup(tb, func(ctx context.Context) (*Clients, error) {
+		return source.Fork(ctx, options...)
+	}, "forked clients")
+}
+
+// databaseSchema is the schema of an existing database.
+type databaseSchema struct {
+	dialect          databasepb.DatabaseDialect
+	ddls             []string
+	protoDescriptors []byte
+}
+
+func readDatabaseSchema(ctx context.Context, dbCli *database.DatabaseAdminClient, databasePath string) (*databaseSchema, error) {
+	db, err := dbCli.GetDatabase(ctx, &databasepb.GetDatabaseRequest{Name: databasePath})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database %s: %w", databasePath, err)
+	}
+	ddl, err := dbCli.GetDatabaseDdl(ctx, &databasepb.GetDatabaseDdlRequest{Database: databasePath})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DDL of database %s: %w", databasePath, err)
+	}
+	return &databaseSchema{
+		dialect:          db.GetDatabaseDialect(),
+		ddls:             ddl.GetStatements(),
+		protoDescriptors: ddl.GetProtoDescriptors(),
+	}, nil
+}
+
+// prependTo makes s the first setup DDLs of finalized opts, so that the setup
+// DDLs of options are applied on top of it. Proto descriptors set by options
+// take precedence.
+func (s *databaseSchema) prependTo(opts *emulatorOptions) {
+	if len(opts.setupDDLSources) > 0 {
+		opts.setupDDLSources = slices.Concat(make([]string, len(s.ddls)), opts.setupDDLSources)
+	}
+	opts.setupDDLs = slices.Concat(s.ddls, opts.setupDDLs)
+	if len(opts.setupFileDescriptorSet) == 0 {
+		opts.setupFileDescriptorSet = s.protoDescriptors
+	}
+}
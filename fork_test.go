@@ -0,0 +1,105 @@
+package spanemuboost
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientsFork(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	base := SetupClients(t, emu,
+		WithRandomDatabaseID(),
+		WithSetupDDLs([]string{"CREATE TABLE tbl (pk INT64, val STRING(MAX)) PRIMARY KEY (pk)"}),
+		WithSetupRawDMLs([]string{"INSERT INTO tbl (pk, val) VALUES (1, 'base')"}),
+	)
+
+	count := func(t *testing.T, clients *Clients) int64 {
+		t.Helper()
+		var n int64
+		err := clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT COUNT(*) FROM tbl")).Do(func(r *spanner.Row) error {
+			return r.Column(0, &n)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	t.Run("schema only", func(t *testing.T) {
+		fork := ForkClients(t, base)
+		if fork.DatabaseID == base.DatabaseID {
+			t.Fatalf("Fork() reused database %q", base.DatabaseID)
+		}
+		if got := count(t, fork); got != 0 {
+			t.Fatalf("COUNT(*) in fork = %d, want 0", got)
+		}
+	})
+
+	t.Run("with data", func(t *testing.T) {
+		fork := ForkClients(t, base, WithForkData(),
+			WithSetupMutations([]*spanner.Mutation{spanner.InsertMap("tbl", map[string]any{"pk": 2, "val": "fork"})}),
+		)
+		if got := count(t, fork); got != 2 {
+			t.Fatalf("COUNT(*) in fork = %d, want 2", got)
+		}
+		if got := count(t, base); got != 1 {
+			t.Fatalf("COUNT(*) in base = %d, want 1", got)
+		}
+	})
+
+	t.Run("dropped on close", func(t *testing.T) {
+		fork, err := base.Fork(t.Context())
+		if err != nil {
+			t.Fatalf("Fork() error = %v", err)
+		}
+		path := fork.DatabasePath()
+		if err := fork.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		_, err = base.DatabaseClient.GetDatabase(t.Context(), &databasepb.GetDatabaseRequest{Name: path})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("GetDatabase() after Close error = %v, want NotFound", err)
+		}
+	})
+}
+
+func TestClientsForkAppliesFixturesAfterCopiedRows(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	base := SetupClients(t, emu,
+		WithRandomDatabaseID(),
+		WithSetupDDLs([]string{
+			"CREATE TABLE Singers (SingerId INT64 NOT NULL, Name STRING(MAX)) PRIMARY KEY (SingerId)",
+			"CREATE TABLE Albums (SingerId INT64 NOT NULL, AlbumId INT64 NOT NULL, Title STRING(MAX)) PRIMARY KEY (SingerId, AlbumId), INTERLEAVE IN PARENT Singers ON DELETE CASCADE",
+		}),
+		WithSetupMutations([]*spanner.Mutation{
+			spanner.Insert("Singers", []string{"SingerId", "Name"}, []any{int64(1), "Base"}),
+		}),
+	)
+
+	// The album is a child of the copied singer, so it can only be inserted
+	// after the copied rows.
+	fork := ForkClients(t, base, WithForkData(), WithSetupFixtures(fstest.MapFS{
+		"Albums.csv": {Data: []byte("SingerId,AlbumId,Title\n1,1,Forked\n")},
+	}, "Albums.csv"))
+
+	var titles []string
+	err := fork.Client.Single().Read(t.Context(), "Albums", spanner.AllKeys(), []string{"Title"}).Do(func(r *spanner.Row) error {
+		var title string
+		if err := r.Column(0, &title); err != nil {
+			return err
+		}
+		titles = append(titles, title)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(titles) != 1 || titles[0] != "Forked" {
+		t.Fatalf("Albums titles in fork = %q, want [Forked]", titles)
+	}
+}
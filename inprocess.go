@@ -0,0 +1,704 @@
+package spanemuboost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"cloud.google.com/go/spanner/apiv1/spannerpb"
+	"cloud.google.com/go/spanner/spannertest"
+	"cloud.google.com/go/spanner/spansql"
+	"google.golang.org/api/option"
+	"google.golang.org/api/option/internaloption"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// inProcessRuntime serves the Spanner gRPC API on a loopback listener from
+// in-memory spannertest fakes, one per database.
+type inProcessRuntime struct {
+	server *inProcessServer
+	opts   *emulatorOptions
+
+	closeState closeState
+}
+
+func (*inProcessRuntime) spanemuboostRuntime() {}
+
+// URI returns the gRPC endpoint (host:port) of the loopback listener.
+func (r *inProcessRuntime) URI() string {
+	return r.server.listener.Addr().String()
+}
+
+// ClientOptions returns [option.ClientOption] values for connecting to the
+// loopback listener without authentication.
+func (r *inProcessRuntime) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint("passthrough:///" + r.URI()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		internaloption.SkipDialSettingsValidation(),
+	}
+}
+
+// Close stops the server and discards every database.
+func (r *inProcessRuntime) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.closeState.close(func() error {
+		if r.opts != nil {
+			r.opts.schemaTemplates.close()
+		}
+		if r.server == nil {
+			return nil
+		}
+		return r.server.close()
+	})
+}
+
+// ProjectID returns the project ID.
+func (r *inProcessRuntime) ProjectID() string { return r.opts.projectID }
+
+// InstanceID returns the instance ID.
+func (r *inProcessRuntime) InstanceID() string { return r.opts.instanceID }
+
+// DatabaseID returns the configured database ID.
+func (r *inProcessRuntime) DatabaseID() string { return r.opts.databaseID }
+
+// ProjectPath returns the project resource path.
+func (r *inProcessRuntime) ProjectPath() string { return r.opts.ProjectPath() }
+
+// InstancePath returns the instance resource path.
+func (r *inProcessRuntime) InstancePath() string { return r.opts.InstancePath() }
+
+// DatabasePath returns the database resource path.
+func (r *inProcessRuntime) DatabasePath() string { return r.opts.DatabasePath() }
+
+func (r *inProcessRuntime) inheritedOptions(options ...Option) (*emulatorOptions, error) {
+	base := inheritedRuntimeOptions(r.opts)
+	base.disableBackendGuardrails = r.opts.disableBackendGuardrails
+	return applyInProcessOptionsWithBase(base, options...)
+}
+
+func (r *inProcessRuntime) runtimePlatform(context.Context) (string, error) {
+	return string(BackendInProcess), nil
+}
+
+func runInProcess(ctx context.Context, options ...Option) (Runtime, error) {
+	opts, err := applyInProcessOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := startInProcess(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := bootstrap(ctx, opts, r.ClientOptions()...); err != nil {
+		return nil, errors.Join(err, r.Close())
+	}
+	return r, nil
+}
+
+func runInProcessWithClients(ctx context.Context, options ...Option) (*RuntimeEnv, error) {
+	opts, err := applyInProcessOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := startInProcess(opts)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := bootstrapAndCreateClientsWithOptions(ctx, r.URI(), opts, r.ClientOptions())
+	if err != nil {
+		return nil, errors.Join(err, r.Close())
+	}
+
+	disableSchemaTeardownUnlessForced(opts, clients)
+
+	return &RuntimeEnv{Clients: clients, runtime: r}, nil
+}
+
+func startInProcess(opts *emulatorOptions) (*inProcessRuntime, error) {
+	server, err := newInProcessServer()
+	if err != nil {
+		return nil, err
+	}
+	enableSchemaTemplates(opts)
+	return &inProcessRuntime{server: server, opts: opts}, nil
+}
+
+func applyInProcessOptions(options ...Option) (*emulatorOptions, error) {
+	return applyInProcessOptionsWithBase(&emulatorOptions{}, options...)
+}
+
+func applyInProcessOptionsWithBase(base *emulatorOptions, options ...Option) (*emulatorOptions, error) {
+	opts, err := applyOptionsWithBase(base, options...)
+	if err != nil {
+		return nil, err
+	}
+	opts.inProcess = true
+	if opts.disableBackendGuardrails {
+		return opts, nil
+	}
+	if opts.databaseDialect == databasepb.DatabaseDialect_POSTGRESQL {
+		return nil, inProcessGuardrailError("the PostgreSQL dialect is unsupported because spannertest implements only GoogleSQL")
+	}
+	if len(opts.setupFileDescriptorSet) > 0 {
+		return nil, inProcessGuardrailError("proto bundles are unsupported because spannertest has no PROTO or ENUM types")
+	}
+	if len(opts.gatewayFlags) > 0 {
+		return nil, inProcessGuardrailError(fmt.Sprintf("emulator gateway flag options are unsupported; got %v", opts.gatewayFlags))
+	}
+	if len(opts.setupFixtures) > 0 {
+		return nil, inProcessGuardrailError("setup fixtures are unsupported because spannertest has no INFORMATION_SCHEMA to read column types from")
+	}
+	ddls := slices.Clone(opts.setupDDLs)
+	for _, m := range opts.migrations {
+		ddls = append(ddls, m.ddls...)
+	}
+	for _, ddl := range ddls {
+		if reason := inProcessUnsupportedDDL(ddl); reason != "" {
+			return nil, inProcessGuardrailError(reason)
+		}
+	}
+	return opts, nil
+}
+
+func inProcessGuardrailError(problem string) error {
+	return fmt.Errorf("spanemuboost: %s by the in-process backend; use BackendEmulator, or DisableBackendGuardrails() to bypass this validation", problem)
+}
+
+// errInformationSchemaUnsupported returns the error of helpers that read
+// INFORMATION_SCHEMA, which spannertest does not implement.
+func errInformationSchemaUnsupported(helper string) error {
+	return fmt.Errorf("spanemuboost: %s is unsupported on BackendInProcess because spannertest has no INFORMATION_SCHEMA; use BackendEmulator", helper)
+}
+
+// requireInformationSchema returns an error for helper if c is served by
+// BackendInProcess.
+func (c *Clients) requireInformationSchema(helper string) error {
+	if c.inProcess {
+		return errInformationSchemaUnsupported(helper)
+	}
+	return nil
+}
+
+// isInProcessRuntime reports whether r is served by BackendInProcess.
+func isInProcessRuntime(r runtimeInstance) bool {
+	switch r := r.(type) {
+	case *inProcessRuntime:
+		return true
+	case *AttachedRuntime:
+		return r.backend == BackendInProcess
+	default:
+		return false
+	}
+}
+
+var (
+	inProcessProtoBundleDDL  = regexp.MustCompile(`(?is)^\s*(?:CREATE|ALTER|DROP)\s+PROTO\s+BUNDLE\b`)
+	inProcessChangeStreamDDL = regexp.MustCompile(`(?is)^\s*(?:CREATE|ALTER|DROP)\s+CHANGE\s+STREAM\b`)
+	inProcessCreateDatabase  = regexp.MustCompile("(?is)^\\s*CREATE\\s+DATABASE\\s+[`\"]?([^`\"\\s]+)[`\"]?\\s*$")
+)
+
+// inProcessUnsupportedDDL explains why spannertest cannot apply ddl, or
+// returns "" if it may.
+func inProcessUnsupportedDDL(ddl string) string {
+	switch {
+	case inProcessProtoBundleDDL.MatchString(ddl):
+		return "proto bundles are unsupported because spannertest has no PROTO or ENUM types"
+	case inProcessChangeStreamDDL.MatchString(ddl):
+		return "change streams are unsupported because spannertest does not implement them"
+	default:
+		return ""
+	}
+}
+
+// inProcessServer implements the instance and database admin APIs itself and
+// forwards the data API to a spannertest fake per database, because a fake
+// serves a single database.
+type inProcessServer struct {
+	listener net.Listener
+	grpc     *grpc.Server
+
+	mu        sync.Mutex
+	instances map[string]*instancepb.Instance
+	databases map[string]*inProcessDatabase
+}
+
+type inProcessDatabase struct {
+	info    *databasepb.Database
+	fake    *spannertest.Server
+	conn    *grpc.ClientConn
+	admin   databasepb.DatabaseAdminClient
+	spanner spannerpb.SpannerClient
+}
+
+func newInProcessServer() (*inProcessServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("spanemuboost: listen for in-process backend: %w", err)
+	}
+	s := &inProcessServer{
+		listener:  listener,
+		grpc:      grpc.NewServer(),
+		instances: make(map[string]*instancepb.Instance),
+		databases: make(map[string]*inProcessDatabase),
+	}
+	instancepb.RegisterInstanceAdminServer(s.grpc, &inProcessInstanceAdmin{s: s})
+	databasepb.RegisterDatabaseAdminServer(s.grpc, &inProcessDatabaseAdmin{s: s})
+	spannerpb.RegisterSpannerServer(s.grpc, &inProcessSpanner{s: s})
+	go func() {
+		_ = s.grpc.Serve(listener)
+	}()
+	return s, nil
+}
+
+func (s *inProcessServer) close() error {
+	s.grpc.Stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for name, db := range s.databases {
+		errs = append(errs, db.close())
+		delete(s.databases, name)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *inProcessServer) database(name string) (*inProcessDatabase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, ok := s.databases[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Database not found: %s", name)
+	}
+	return db, nil
+}
+
+// session resolves a session name returned by [inProcessSpanner] to its
+// database and the session ID known to the fake.
+func (s *inProcessServer) session(name string) (*inProcessDatabase, string, error) {
+	databaseName, id, ok := strings.Cut(name, "/sessions/")
+	if !ok {
+		return nil, "", status.Errorf(codes.InvalidArgument, "invalid session name %q", name)
+	}
+	db, err := s.database(databaseName)
+	if err != nil {
+		return nil, "", err
+	}
+	return db, id, nil
+}
+
+func newInProcessDatabase(name string) (*inProcessDatabase, error) {
+	fake, err := spannertest.NewServer("127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	fake.SetLogger(func(string, ...any) {})
+	conn, err := grpc.NewClient("passthrough:///"+fake.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fake.Close()
+		return nil, err
+	}
+	return &inProcessDatabase{
+		info: &databasepb.Database{
+			Name:            name,
+			State:           databasepb.Database_READY,
+			CreateTime:      timestamppb.Now(),
+			DatabaseDialect: databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL,
+		},
+		fake:    fake,
+		conn:    conn,
+		admin:   databasepb.NewDatabaseAdminClient(conn),
+		spanner: spannerpb.NewSpannerClient(conn),
+	}, nil
+}
+
+func (db *inProcessDatabase) close() error {
+	err := db.conn.Close()
+	db.fake.Close()
+	return err
+}
+
+// applyDDL applies ddls one at a time, so that the failing statement is known,
+// and returns the commit timestamps of the applied statements.
+func (db *inProcessDatabase) applyDDL(ddls []string) ([]*timestamppb.Timestamp, error) {
+	var timestamps []*timestamppb.Timestamp
+	for _, ddl := range ddls {
+		if reason := inProcessUnsupportedDDL(ddl); reason != "" {
+			return timestamps, status.Errorf(codes.Unimplemented, "%s by the in-process backend", reason)
+		}
+		stmt, err := spansql.ParseDDLStmt(ddl)
+		if err != nil {
+			return timestamps, status.Errorf(codes.InvalidArgument, "spannertest cannot parse DDL statement: %v", err)
+		}
+		if err := db.fake.UpdateDDL(&spansql.DDL{List: []spansql.DDLStmt{stmt}}); err != nil {
+			return timestamps, err
+		}
+		timestamps = append(timestamps, timestamppb.Now())
+	}
+	return timestamps, nil
+}
+
+// doneOperation returns a completed long-running operation with response, or
+// with err if it is non-nil.
+func doneOperation(name string, metadata, response proto.Message, err error) (*longrunningpb.Operation, error) {
+	op := &longrunningpb.Operation{Name: name + "/operations/" + generateRandomID(), Done: true}
+	if metadata != nil {
+		md, mdErr := anypb.New(metadata)
+		if mdErr != nil {
+			return nil, mdErr
+		}
+		op.Metadata = md
+	}
+	if err != nil {
+		op.Result = &longrunningpb.Operation_Error{Error: status.Convert(err).Proto()}
+		return op, nil
+	}
+	resp, respErr := anypb.New(response)
+	if respErr != nil {
+		return nil, respErr
+	}
+	op.Result = &longrunningpb.Operation_Response{Response: resp}
+	return op, nil
+}
+
+type inProcessInstanceAdmin struct {
+	instancepb.UnimplementedInstanceAdminServer
+	s *inProcessServer
+}
+
+func (a *inProcessInstanceAdmin) CreateInstance(_ context.Context, req *instancepb.CreateInstanceRequest) (*longrunningpb.Operation, error) {
+	name := req.GetParent() + "/instances/" + req.GetInstanceId()
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	if _, ok := a.s.instances[name]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "Instance already exists: %s", name)
+	}
+	inst := proto.Clone(req.GetInstance()).(*instancepb.Instance)
+	if inst == nil {
+		inst = &instancepb.Instance{}
+	}
+	inst.Name = name
+	inst.State = instancepb.Instance_READY
+	a.s.instances[name] = inst
+	return doneOperation(name, &instancepb.CreateInstanceMetadata{Instance: inst}, inst, nil)
+}
+
+func (a *inProcessInstanceAdmin) GetInstance(_ context.Context, req *instancepb.GetInstanceRequest) (*instancepb.Instance, error) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	inst, ok := a.s.instances[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Instance not found: %s", req.GetName())
+	}
+	return inst, nil
+}
+
+func (a *inProcessInstanceAdmin) ListInstances(_ context.Context, req *instancepb.ListInstancesRequest) (*instancepb.ListInstancesResponse, error) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	resp := &instancepb.ListInstancesResponse{}
+	for name, inst := range a.s.instances {
+		if strings.HasPrefix(name, req.GetParent()+"/instances/") {
+			resp.Instances = append(resp.Instances, inst)
+		}
+	}
+	slices.SortFunc(resp.Instances, func(a, b *instancepb.Instance) int { return strings.Compare(a.GetName(), b.GetName()) })
+	return resp, nil
+}
+
+func (a *inProcessInstanceAdmin) DeleteInstance(_ context.Context, req *instancepb.DeleteInstanceRequest) (*emptypb.Empty, error) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	if _, ok := a.s.instances[req.GetName()]; !ok {
+		return nil, status.Errorf(codes.NotFound, "Instance not found: %s", req.GetName())
+	}
+	delete(a.s.instances, req.GetName())
+	var errs []error
+	for name, db := range a.s.databases {
+		if strings.HasPrefix(name, req.GetName()+"/databases/") {
+			errs = append(errs, db.close())
+			delete(a.s.databases, name)
+		}
+	}
+	return &emptypb.Empty{}, errors.Join(errs...)
+}
+
+type inProcessDatabaseAdmin struct {
+	databasepb.UnimplementedDatabaseAdminServer
+	s *inProcessServer
+}
+
+func (a *inProcessDatabaseAdmin) CreateDatabase(_ context.Context, req *databasepb.CreateDatabaseRequest) (*longrunningpb.Operation, error) {
+	m := inProcessCreateDatabase.FindStringSubmatch(req.GetCreateStatement())
+	if m == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid create statement %q", req.GetCreateStatement())
+	}
+	if req.GetDatabaseDialect() == databasepb.DatabaseDialect_POSTGRESQL {
+		return nil, status.Error(codes.Unimplemented, "the PostgreSQL dialect is unsupported by the in-process backend")
+	}
+	if len(req.GetProtoDescriptors()) > 0 {
+		return nil, status.Error(codes.Unimplemented, "proto bundles are unsupported by the in-process backend")
+	}
+	name := req.GetParent() + "/databases/" + m[1]
+
+	a.s.mu.Lock()
+	_, instanceExists := a.s.instances[req.GetParent()]
+	_, databaseExists := a.s.databases[name]
+	a.s.mu.Unlock()
+	switch {
+	case !instanceExists:
+		return nil, status.Errorf(codes.NotFound, "Instance not found: %s", req.GetParent())
+	case databaseExists:
+		return nil, status.Errorf(codes.AlreadyExists, "Database already exists: %s", name)
+	}
+
+	db, err := newInProcessDatabase(name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "start spannertest: %v", err)
+	}
+	metadata := &databasepb.CreateDatabaseMetadata{Database: name}
+	if _, err := db.applyDDL(req.GetExtraStatements()); err != nil {
+		return doneOperation(name, metadata, nil, errors.Join(err, db.close()))
+	}
+
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	if _, ok := a.s.databases[name]; ok {
+		return nil, errors.Join(status.Errorf(codes.AlreadyExists, "Database already exists: %s", name), db.close())
+	}
+	a.s.databases[name] = db
+	return doneOperation(name, metadata, db.info, nil)
+}
+
+func (a *inProcessDatabaseAdmin) GetDatabase(_ context.Context, req *databasepb.GetDatabaseRequest) (*databasepb.Database, error) {
+	db, err := a.s.database(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return db.info, nil
+}
+
+func (a *inProcessDatabaseAdmin) ListDatabases(_ context.Context, req *databasepb.ListDatabasesRequest) (*databasepb.ListDatabasesResponse, error) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	resp := &databasepb.ListDatabasesResponse{}
+	for name, db := range a.s.databases {
+		if strings.HasPrefix(name, req.GetParent()+"/databases/") {
+			resp.Databases = append(resp.Databases, db.info)
+		}
+	}
+	slices.SortFunc(resp.Databases, func(a, b *databasepb.Database) int { return strings.Compare(a.GetName(), b.GetName()) })
+	return resp, nil
+}
+
+func (a *inProcessDatabaseAdmin) DropDatabase(_ context.Context, req *databasepb.DropDatabaseRequest) (*emptypb.Empty, error) {
+	a.s.mu.Lock()
+	db, ok := a.s.databases[req.GetDatabase()]
+	delete(a.s.databases, req.GetDatabase())
+	a.s.mu.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Database not found: %s", req.GetDatabase())
+	}
+	return &emptypb.Empty{}, db.close()
+}
+
+func (a *inProcessDatabaseAdmin) UpdateDatabaseDdl(_ context.Context, req *databasepb.UpdateDatabaseDdlRequest) (*longrunningpb.Operation, error) {
+	db, err := a.s.database(req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetProtoDescriptors()) > 0 {
+		return nil, status.Error(codes.Unimplemented, "proto bundles are unsupported by the in-process backend")
+	}
+	timestamps, err := db.applyDDL(req.GetStatements())
+	metadata := &databasepb.UpdateDatabaseDdlMetadata{
+		Database:         req.GetDatabase(),
+		Statements:       req.GetStatements(),
+		CommitTimestamps: timestamps,
+	}
+	return doneOperation(req.GetDatabase(), metadata, &emptypb.Empty{}, err)
+}
+
+func (a *inProcessDatabaseAdmin) GetDatabaseDdl(ctx context.Context, req *databasepb.GetDatabaseDdlRequest) (*databasepb.GetDatabaseDdlResponse, error) {
+	db, err := a.s.database(req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return db.admin.GetDatabaseDdl(ctx, req)
+}
+
+// inProcessSpanner forwards the data API to the fake of each database. The
+// fakes name sessions with bare IDs, so sessions are renamed to
+// "<database>/sessions/<id>" on the way out and back on the way in.
+type inProcessSpanner struct {
+	spannerpb.UnimplementedSpannerServer
+	s *inProcessServer
+}
+
+// route resolves the session named by field of req and rewrites it to the ID
+// known to the fake.
+func (p *inProcessSpanner) route(req proto.Message, field protoreflect.Name) (*inProcessDatabase, error) {
+	m := req.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(field)
+	db, id, err := p.s.session(m.Get(fd).String())
+	if err != nil {
+		return nil, err
+	}
+	m.Set(fd, protoreflect.ValueOfString(id))
+	return db, nil
+}
+
+func forwardUnary[Req proto.Message, Resp any](ctx context.Context, p *inProcessSpanner, req Req, call func(spannerpb.SpannerClient, context.Context, Req, ...grpc.CallOption) (Resp, error)) (Resp, error) {
+	db, err := p.route(req, "session")
+	if err != nil {
+		var zero Resp
+		return zero, err
+	}
+	return call(db.spanner, ctx, req)
+}
+
+func forwardStream[Resp any](recv func() (Resp, error), send func(Resp) error) error {
+	for {
+		msg, err := recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func renameSession(databaseName string, session *spannerpb.Session) *spannerpb.Session {
+	session.Name = databaseName + "/sessions/" + session.GetName()
+	return session
+}
+
+func (p *inProcessSpanner) CreateSession(ctx context.Context, req *spannerpb.CreateSessionRequest) (*spannerpb.Session, error) {
+	db, err := p.s.database(req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	session, err := db.spanner.CreateSession(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return renameSession(req.GetDatabase(), session), nil
+}
+
+func (p *inProcessSpanner) BatchCreateSessions(ctx context.Context, req *spannerpb.BatchCreateSessionsRequest) (*spannerpb.BatchCreateSessionsResponse, error) {
+	db, err := p.s.database(req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := db.spanner.BatchCreateSessions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range resp.GetSession() {
+		renameSession(req.GetDatabase(), session)
+	}
+	return resp, nil
+}
+
+func (p *inProcessSpanner) GetSession(ctx context.Context, req *spannerpb.GetSessionRequest) (*spannerpb.Session, error) {
+	databaseName, _, _ := strings.Cut(req.GetName(), "/sessions/")
+	db, err := p.route(req, "name")
+	if err != nil {
+		return nil, err
+	}
+	session, err := db.spanner.GetSession(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return renameSession(databaseName, session), nil
+}
+
+func (p *inProcessSpanner) DeleteSession(ctx context.Context, req *spannerpb.DeleteSessionRequest) (*emptypb.Empty, error) {
+	db, err := p.route(req, "name")
+	if err != nil {
+		return nil, err
+	}
+	return db.spanner.DeleteSession(ctx, req)
+}
+
+func (p *inProcessSpanner) ExecuteSql(ctx context.Context, req *spannerpb.ExecuteSqlRequest) (*spannerpb.ResultSet, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.ExecuteSql)
+}
+
+func (p *inProcessSpanner) ExecuteStreamingSql(req *spannerpb.ExecuteSqlRequest, server spannerpb.Spanner_ExecuteStreamingSqlServer) error {
+	stream, err := forwardUnary(server.Context(), p, req, spannerpb.SpannerClient.ExecuteStreamingSql)
+	if err != nil {
+		return err
+	}
+	return forwardStream(stream.Recv, server.Send)
+}
+
+func (p *inProcessSpanner) ExecuteBatchDml(ctx context.Context, req *spannerpb.ExecuteBatchDmlRequest) (*spannerpb.ExecuteBatchDmlResponse, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.ExecuteBatchDml)
+}
+
+func (p *inProcessSpanner) Read(ctx context.Context, req *spannerpb.ReadRequest) (*spannerpb.ResultSet, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.Read)
+}
+
+func (p *inProcessSpanner) StreamingRead(req *spannerpb.ReadRequest, server spannerpb.Spanner_StreamingReadServer) error {
+	stream, err := forwardUnary(server.Context(), p, req, spannerpb.SpannerClient.StreamingRead)
+	if err != nil {
+		return err
+	}
+	return forwardStream(stream.Recv, server.Send)
+}
+
+func (p *inProcessSpanner) BeginTransaction(ctx context.Context, req *spannerpb.BeginTransactionRequest) (*spannerpb.Transaction, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.BeginTransaction)
+}
+
+func (p *inProcessSpanner) Commit(ctx context.Context, req *spannerpb.CommitRequest) (*spannerpb.CommitResponse, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.Commit)
+}
+
+func (p *inProcessSpanner) Rollback(ctx context.Context, req *spannerpb.RollbackRequest) (*emptypb.Empty, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.Rollback)
+}
+
+func (p *inProcessSpanner) PartitionQuery(ctx context.Context, req *spannerpb.PartitionQueryRequest) (*spannerpb.PartitionResponse, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.PartitionQuery)
+}
+
+func (p *inProcessSpanner) PartitionRead(ctx context.Context, req *spannerpb.PartitionReadRequest) (*spannerpb.PartitionResponse, error) {
+	return forwardUnary(ctx, p, req, spannerpb.SpannerClient.PartitionRead)
+}
+
+func (p *inProcessSpanner) BatchWrite(req *spannerpb.BatchWriteRequest, server spannerpb.Spanner_BatchWriteServer) error {
+	stream, err := forwardUnary(server.Context(), p, req, spannerpb.SpannerClient.BatchWrite)
+	if err != nil {
+		return err
+	}
+	return forwardStream(stream.Recv, server.Send)
+}
@@ -0,0 +1,186 @@
+package spanemuboost
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var inProcessTestDDLs = []string{
+	"CREATE TABLE Singers (SingerId INT64 NOT NULL, Name STRING(MAX)) PRIMARY KEY (SingerId)",
+}
+
+func readSingerNames(t *testing.T, client *spanner.Client) []string {
+	t.Helper()
+	var names []string
+	iter := client.Single().Read(t.Context(), "Singers", spanner.AllKeys(), []string{"Name"})
+	defer iter.Stop()
+	for {
+		row, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var name string
+		if err := row.Columns(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+}
+
+func TestSetupWithClientsInProcess(t *testing.T) {
+	env := SetupWithClients(t, BackendInProcess,
+		WithSetupDDLs(inProcessTestDDLs),
+		WithSetupMutations([]*spanner.Mutation{
+			spanner.Insert("Singers", []string{"SingerId", "Name"}, []any{int64(1), "Alice"}),
+		}),
+	)
+
+	if diff := cmp.Diff([]string{"Alice"}, readSingerNames(t, env.Client)); diff != "" {
+		t.Fatalf("rows mismatch (-want +got):\n%s", diff)
+	}
+	platform, err := RuntimePlatform(t.Context(), env.Runtime())
+	if err != nil || platform != string(BackendInProcess) {
+		t.Fatalf("RuntimePlatform() = %q, %v; want %q", platform, err, BackendInProcess)
+	}
+}
+
+func TestOpenClientsInProcessIsolatesDatabases(t *testing.T) {
+	runtime := NewLazyRuntime(BackendInProcess, EnableInstanceAutoConfigOnly())
+	t.Cleanup(func() {
+		if err := runtime.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	var dbs []*Clients
+	for _, name := range []string{"Alice", "Bob"} {
+		clients := SetupClients(t, runtime,
+			WithRandomDatabaseID(),
+			ForceSchemaTeardown(),
+			WithSetupDDLs(inProcessTestDDLs),
+		)
+		if _, err := clients.Client.Apply(t.Context(), []*spanner.Mutation{
+			spanner.Insert("Singers", []string{"SingerId", "Name"}, []any{int64(1), name}),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		dbs = append(dbs, clients)
+	}
+
+	for i, want := range []string{"Alice", "Bob"} {
+		if diff := cmp.Diff([]string{want}, readSingerNames(t, dbs[i].Client)); diff != "" {
+			t.Fatalf("database %d rows mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	path := dbs[0].DatabasePath()
+	if err := dbs[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbs[1].DatabaseClient.GetDatabase(t.Context(), &databasepb.GetDatabaseRequest{Name: path}); status.Code(err) != codes.NotFound {
+		t.Fatalf("GetDatabase() after Close error = %v, want NotFound", err)
+	}
+}
+
+func TestInProcessReportsFailingDDLStatement(t *testing.T) {
+	runtime := Setup(t, BackendInProcess, EnableInstanceAutoConfigOnly())
+	_, err := OpenClients(t.Context(), runtime,
+		WithRandomDatabaseID(),
+		ForceSchemaTeardown(),
+		WithSetupDDLs(inProcessTestDDLs),
+		WithSetupSteps(DDLStep(
+			"CREATE INDEX SingersByName ON Singers (Name)",
+			"ALTER TABLE Missing ADD COLUMN Name STRING(MAX)",
+		)),
+	)
+	var stmtErr *SetupStatementError
+	if !errors.As(err, &stmtErr) {
+		t.Fatalf("DDLStep error = %v, want a SetupStatementError", err)
+	}
+	if stmtErr.Index != 1 {
+		t.Fatalf("failing statement index = %d, want 1", stmtErr.Index)
+	}
+}
+
+func TestInProcessGuardrails(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{
+			name:    "PostgreSQL dialect",
+			options: []Option{WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL)},
+			want:    "PostgreSQL dialect is unsupported",
+		},
+		{
+			name:    "proto bundle",
+			options: []Option{WithSetupDDLs([]string{"CREATE PROTO BUNDLE (`examples.shipping.Order`)"})},
+			want:    "proto bundles are unsupported",
+		},
+		{
+			name:    "change stream",
+			options: []Option{WithSetupDDLs([]string{"CREATE CHANGE STREAM Everything FOR ALL"})},
+			want:    "change streams are unsupported",
+		},
+		{
+			name: "setup fixtures",
+			options: []Option{
+				WithSetupDDLs(inProcessTestDDLs),
+				WithSetupFixtures(fstest.MapFS{"Singers.csv": {Data: []byte("SingerId,Name\n1,Alice\n")}}, "*.csv"),
+			},
+			want: "setup fixtures are unsupported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Run(t.Context(), BackendInProcess, tt.options...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "BackendEmulator") {
+				t.Fatalf("Run() error = %v, want %q with a pointer to BackendEmulator", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInProcessRejectsInformationSchemaHelpers(t *testing.T) {
+	runtime := Setup(t, BackendInProcess, EnableInstanceAutoConfigOnly())
+	clients := SetupClients(t, runtime, WithRandomDatabaseID(), WithSetupDDLs(inProcessTestDDLs))
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "Clients.Schema", call: func() error { _, err := clients.Schema(t.Context()); return err }},
+		{name: "Clients.Snapshot", call: func() error { _, err := clients.Snapshot(t.Context()); return err }},
+		{name: "Clients.Restore", call: func() error { return clients.Restore(t.Context(), &Snapshot{}) }},
+		{name: "Clients.ResetData", call: func() error { return clients.ResetData(t.Context()) }},
+		{name: "DumpDatabase", call: func() error { _, err := DumpDatabase(t.Context(), clients); return err }},
+		{name: "DumpRuntime", call: func() error { _, err := DumpRuntime(t.Context(), runtime); return err }},
+		{name: "WithCopyRows", call: func() error {
+			_, err := CopyDatabase(t.Context(), clients, runtime, WithRandomDatabaseID(), WithCopyRows(0))
+			return err
+		}},
+		{name: "WithForkData", call: func() error { _, err := clients.Fork(t.Context(), WithForkData()); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			want := tt.name + " is unsupported on BackendInProcess"
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("error = %v, want %q", err, want)
+			}
+		})
+	}
+}
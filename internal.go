@@ -103,12 +103,12 @@ func ociPlatformString(platform *ocispec.Platform) string {
 	return platform.OS + "/" + platform.Architecture + "/" + platform.Variant
 }
 
-// executeDMLs creates a short-lived internal client solely for bootstrap DML
-// execution in the deprecated path. It uses a minimal config intentionally:
-// the user-provided ClientConfig is not applied here because this client is
-// discarded immediately after DMLs complete. In the new API path,
-// executeDMLsWithClient is used instead with the user-facing client.
-func executeDMLs(ctx context.Context, opts *emulatorOptions, clientOpts ...option.ClientOption) error {
+// seedDatabase creates a short-lived internal client solely for bootstrap
+// fixture and DML execution in the deprecated path. It uses a minimal config
+// intentionally: the user-provided ClientConfig is not applied here because
+// this client is discarded immediately after seeding completes. In the new API
+// path, seedDatabaseWithClient is used instead with the user-facing client.
+func seedDatabase(ctx context.Context, opts *emulatorOptions, clientOpts ...option.ClientOption) error {
 	client, err := spanner.NewClientWithConfig(ctx, opts.DatabasePath(),
 		minimalBootstrapClientConfig(spanner.ClientConfig{
 			// This deprecated bootstrap path creates its own throwaway client and
@@ -124,24 +124,60 @@ func executeDMLs(ctx context.Context, opts *emulatorOptions, clientOpts ...optio
 
 	defer client.Close()
 
-	_, err = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
-		_, err = txn.BatchUpdate(ctx, opts.setupDMLs)
-		return err
-	})
-	if err != nil {
-		return fmt.Errorf("failed to apply DML: %w", err)
-	}
+	return seedDatabaseWithClient(ctx, opts, client)
+}
 
+// seedDatabaseWithClient writes setup data: rows copied by Fork or
+// CopyDatabase first, then fixtures, then mutations, then DMLs.
+func seedDatabaseWithClient(ctx context.Context, opts *emulatorOptions, client *spanner.Client) error {
+	if len(opts.copiedRows) > 0 {
+		if err := applyMutationChunks(ctx, client, "copied rows", opts.copiedRows, opts.setupBatchSizeOrDefault(), nil); err != nil {
+			return err
+		}
+	}
+	if len(opts.setupFixtures) > 0 {
+		if err := applyFixtures(ctx, opts, client); err != nil {
+			return err
+		}
+	}
+	if len(opts.setupMutations) > 0 {
+		if err := applyMutationChunks(ctx, client, "mutations", opts.setupMutations, opts.setupBatchSizeOrDefault(), opts.setupMutationBoundaries); err != nil {
+			return err
+		}
+	}
+	if len(opts.setupDMLs) > 0 {
+		return executeDMLsWithClient(ctx, opts, client)
+	}
 	return nil
 }
 
 func executeDMLsWithClient(ctx context.Context, opts *emulatorOptions, client *spanner.Client) error {
-	_, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
-		_, err := txn.BatchUpdate(ctx, opts.setupDMLs)
-		return err
-	})
-	if err != nil {
-		return fmt.Errorf("failed to apply DML: %w", err)
+	return executeDMLChunks(ctx, client, opts.setupDMLs, opts.setupDMLSources, opts.setupBatchSizeOrDefault(), opts.setupDMLBoundaries)
+}
+
+// executeDMLChunks executes stmts in chunked read-write transactions. sources
+// optionally holds the file location of each statement for error messages.
+func executeDMLChunks(ctx context.Context, client *spanner.Client, stmts []spanner.Statement, sources []string, size int, boundaries []int) error {
+	chunks := setupChunks(len(stmts), size, boundaries)
+	for i, c := range chunks {
+		// BatchUpdate returns the counts of the statements that succeeded before
+		// the failing one, which identifies the failing statement.
+		failed := -1
+		_, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+			counts, err := txn.BatchUpdate(ctx, stmts[c.start:c.end])
+			if err != nil {
+				failed = c.start + len(counts)
+			}
+			return err
+		})
+		if err != nil {
+			if failed < 0 || failed >= c.end {
+				return fmt.Errorf("failed to commit DML at indexes %d-%d (chunk %d of %d): %w", c.start, c.end-1, i+1, len(chunks), err)
+			}
+			stmtErr := newSetupStatementError(SetupStageDML, failed, stmts[failed].SQL, sources, err)
+			stmtErr.Chunk, stmtErr.Chunks = i+1, len(chunks)
+			return stmtErr
+		}
 	}
 	return nil
 }
@@ -153,14 +189,39 @@ func minimalBootstrapClientConfig(config spanner.ClientConfig) spanner.ClientCon
 	return cfg
 }
 
+// updateDDLs applies setup DDL work to an existing database: migrations first,
+// each as its own operation, followed by the setup DDLs.
 func updateDDLs(ctx context.Context, opts *emulatorOptions, dbCli *database.DatabaseAdminClient) error {
-	op, err := dbCli.UpdateDatabaseDdl(ctx, updateDatabaseDdlRequest(opts))
+	if err := applyMigrations(ctx, opts, dbCli); err != nil {
+		return err
+	}
+	if len(opts.setupDDLs) == 0 {
+		return nil
+	}
+	return updateDDLStatements(ctx, opts, dbCli, opts.setupDDLs, opts.setupDDLSources)
+}
+
+func updateDDLStatements(ctx context.Context, opts *emulatorOptions, dbCli *database.DatabaseAdminClient, ddls, sources []string) error {
+	op, err := dbCli.UpdateDatabaseDdl(ctx, updateDatabaseDdlRequest(opts, ddls))
 	if err != nil {
 		return err
 	}
 
 	if err := op.Wait(ctx); err != nil {
-		return fmt.Errorf("failed to apply DDL: %w", err)
+		// Each successfully applied statement has a commit timestamp, so the
+		// first statement without one is the one that failed.
+		failed := -1
+		stmts := ddls
+		if md, mdErr := op.Metadata(); mdErr == nil && md != nil {
+			failed = len(md.GetCommitTimestamps())
+			if len(md.GetStatements()) > 0 {
+				stmts = md.GetStatements()
+			}
+		}
+		if failed < 0 || failed >= len(stmts) {
+			return newSetupStatementError(SetupStageDDL, -1, "", sources, err)
+		}
+		return newSetupStatementError(SetupStageDDL, failed, stmts[failed], sources, err)
 	}
 	return nil
 }
@@ -176,11 +237,24 @@ func bootstrapInstance(ctx context.Context, opts *emulatorOptions, instanceCli *
 	return true, nil
 }
 
-// bootstrapDatabase creates the database (with DDLs) or applies DDLs to an existing database.
-// It reports whether this call created the database.
+// bootstrapDatabase creates the database (with DDLs) or applies DDLs to an
+// existing database. It reports whether this call created the database.
 func bootstrapDatabase(ctx context.Context, opts *emulatorOptions, dbCli *database.DatabaseAdminClient) (bool, error) {
 	if !opts.disableCreateDatabase {
-		return createDatabase(ctx, opts, dbCli)
+		extraStatements := createDatabaseExtraStatements(opts)
+		created, err := createDatabase(ctx, opts, dbCli, extraStatements)
+		if err != nil && len(extraStatements) > 0 {
+			return locateCreateDatabaseFailure(ctx, opts, dbCli, err)
+		}
+		if err == nil && !created && opts.requireNewDatabase {
+			return false, fmt.Errorf("database %s already exists", opts.DatabasePath())
+		}
+		if err != nil || !created || len(opts.migrations) == 0 {
+			return created, err
+		}
+		// Setup DDLs are not sent with CreateDatabase when migrations are
+		// configured, so that they are applied on top of the migrations.
+		return true, updateDDLs(ctx, opts, dbCli)
 	}
 	if opts.hasSetupDDLWork() {
 		return false, updateDDLs(ctx, opts, dbCli)
@@ -221,12 +295,20 @@ func bootstrap(ctx context.Context, opts *emulatorOptions, clientOpts ...option.
 		}
 	}
 
-	if len(opts.setupDMLs) > 0 {
-		if err := executeDMLs(ctx, opts, clientOpts...); err != nil {
+	if opts.hasSetupDataWork() {
+		if err := seedDatabase(ctx, opts, clientOpts...); err != nil {
 			return err
 		}
 	}
 
+	if len(opts.setupSteps) > 0 {
+		// Like seedDatabase, use a minimal throwaway config with native metrics
+		// disabled explicitly.
+		return runSetupStepsWithTemporaryClients(ctx, opts, clientOpts, minimalBootstrapClientConfig(spanner.ClientConfig{
+			DisableNativeMetrics: true,
+		}))
+	}
+
 	return nil
 }
 
@@ -262,21 +344,41 @@ func bootstrapWithManagedClientConfig(ctx context.Context, opts *emulatorOptions
 			return err
 		}
 	}
-	if len(opts.setupDMLs) == 0 {
+	clientConfig := minimalBootstrapClientConfig(*opts.clientConfig)
+	if opts.hasSetupDataWork() {
+		client, err := spanner.NewClientWithConfig(ctx, opts.DatabasePath(), clientConfig, slices.Concat(clientOpts, opts.clientOptionsForClient)...)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		if err := seedDatabaseWithClient(ctx, opts, client); err != nil {
+			return err
+		}
+	}
+	if len(opts.setupSteps) == 0 {
 		return nil
 	}
+	return runSetupStepsWithTemporaryClients(ctx, opts, slices.Concat(clientOpts, opts.clientOptionsForClient), clientConfig)
+}
 
-	clientConfig := minimalBootstrapClientConfig(*opts.clientConfig)
-	client, err := spanner.NewClientWithConfig(ctx, opts.DatabasePath(), clientConfig, slices.Concat(clientOpts, opts.clientOptionsForClient)...)
-	if err != nil {
-		return err
+// locateCreateDatabaseFailure finds the setup DDL that failed CreateDatabase,
+// which does not report which of its extra statements failed. The failed
+// operation created nothing, so it creates the database again without them and
+// applies them with UpdateDatabaseDdl, which does. createErr is returned if
+// the statements apply this time.
+func locateCreateDatabaseFailure(ctx context.Context, opts *emulatorOptions, dbCli *database.DatabaseAdminClient, createErr error) (bool, error) {
+	created, err := createDatabase(ctx, opts, dbCli, nil)
+	if err != nil || !created {
+		return created, createErr
 	}
-	defer client.Close()
-
-	return executeDMLsWithClient(ctx, opts, client)
+	if err := updateDDLs(ctx, opts, dbCli); err != nil {
+		return true, err
+	}
+	return true, createErr
 }
 
-func createDatabase(ctx context.Context, opts *emulatorOptions, dbCli *database.DatabaseAdminClient) (bool, error) {
+func createDatabase(ctx context.Context, opts *emulatorOptions, dbCli *database.DatabaseAdminClient, extraStatements []string) (bool, error) {
 	var createStmt string
 	if opts.databaseDialect != databasepb.DatabaseDialect_POSTGRESQL {
 		createStmt = fmt.Sprintf("CREATE DATABASE `%v`", opts.databaseID)
@@ -286,7 +388,7 @@ func createDatabase(ctx context.Context, opts *emulatorOptions, dbCli *database.
 	instancePath := opts.InstancePath()
 	var err error
 	if lockErr := withInstanceAdminLock(ctx, instancePath, func() {
-		createDBOp, createErr := dbCli.CreateDatabase(ctx, createDatabaseRequest(opts, instancePath, createStmt))
+		createDBOp, createErr := dbCli.CreateDatabase(ctx, createDatabaseRequest(opts, instancePath, createStmt, extraStatements))
 		if createErr != nil {
 			err = createErr
 			return
@@ -299,25 +401,37 @@ func createDatabase(ctx context.Context, opts *emulatorOptions, dbCli *database.
 		if status.Code(err) == codes.AlreadyExists {
 			return false, nil
 		}
-		return false, err
+		return false, newSetupStatementError(SetupStageCreateDatabase, -1, "", nil, err)
 	}
 	return true, nil
 }
 
-func createDatabaseRequest(opts *emulatorOptions, parent, createStmt string) *databasepb.CreateDatabaseRequest {
-	return &databasepb.CreateDatabaseRequest{
-		Parent:           parent,
-		CreateStatement:  createStmt,
-		DatabaseDialect:  opts.databaseDialect,
-		ExtraStatements:  opts.setupDDLs,
-		ProtoDescriptors: opts.setupFileDescriptorSet,
+// createDatabaseRequest returns the CreateDatabase request for the database of
+// opts. The proto descriptors are sent only with the statements that use them.
+func createDatabaseRequest(opts *emulatorOptions, parent, createStmt string, extraStatements []string) *databasepb.CreateDatabaseRequest {
+	req := &databasepb.CreateDatabaseRequest{
+		Parent:          parent,
+		CreateStatement: createStmt,
+		DatabaseDialect: opts.databaseDialect,
+		ExtraStatements: extraStatements,
+	}
+	if len(extraStatements) > 0 {
+		req.ProtoDescriptors = opts.setupFileDescriptorSet
+	}
+	return req
+}
+
+func createDatabaseExtraStatements(opts *emulatorOptions) []string {
+	if len(opts.migrations) > 0 {
+		return nil
 	}
+	return opts.setupDDLs
 }
 
-func updateDatabaseDdlRequest(opts *emulatorOptions) *databasepb.UpdateDatabaseDdlRequest {
+func updateDatabaseDdlRequest(opts *emulatorOptions, ddls []string) *databasepb.UpdateDatabaseDdlRequest {
 	return &databasepb.UpdateDatabaseDdlRequest{
 		Database:         opts.DatabasePath(),
-		Statements:       opts.setupDDLs,
+		Statements:       ddls,
 		ProtoDescriptors: opts.setupFileDescriptorSet,
 	}
 }
@@ -386,9 +500,24 @@ func bootstrapAndCreateClientsWithOptions(ctx context.Context, uri string, opts
 		return nil, err
 	}
 
-	createdResources.database, err = bootstrapDatabase(ctx, opts, dbCli)
-	if err != nil {
-		return nil, err
+	var templateKey string
+	seedOpts := opts
+	if opts.schemaTemplateEligible() {
+		templateKey = schemaTemplateKey(opts)
+		if id, ok := opts.schemaTemplates.take(templateKey); ok {
+			opts.databaseID = id
+			createdResources.database = true
+			// The prepared database already has the schema and fixtures.
+			withoutFixtures := *opts
+			withoutFixtures.setupFixtures = nil
+			seedOpts = &withoutFixtures
+		}
+	}
+	if !createdResources.database {
+		createdResources.database, err = bootstrapDatabase(ctx, opts, dbCli)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	client, err = spanner.NewClientWithConfig(ctx, opts.DatabasePath(), *opts.clientConfig, slices.Concat(clientOpts, opts.clientOptionsForClient)...)
@@ -396,14 +525,17 @@ func bootstrapAndCreateClientsWithOptions(ctx context.Context, uri string, opts
 		return nil, err
 	}
 
-	if len(opts.setupDMLs) > 0 {
-		if err := executeDMLsWithClient(ctx, opts, client); err != nil {
+	if seedOpts.hasSetupDataWork() {
+		if err := seedDatabaseWithClient(ctx, seedOpts, client); err != nil {
 			return nil, err
 		}
 	}
+	if templateKey != "" && createdResources.database {
+		opts.schemaTemplates.register(templateKey, opts, clientOpts)
+	}
 
 	forceTeardown := opts.schemaTeardown != nil && *opts.schemaTeardown
-	return &Clients{
+	clients := &Clients{
 		InstanceClient: instanceCli,
 		DatabaseClient: dbCli,
 		Client:         client,
@@ -414,7 +546,16 @@ func bootstrapAndCreateClientsWithOptions(ctx context.Context, uri string, opts
 		uri:            uri,
 		dropDatabase:   opts.shouldDropDatabase() && (createdResources.database || forceTeardown),
 		dropInstance:   opts.shouldDropInstance() && (createdResources.instance || forceTeardown),
-	}, nil
+		inProcess:      opts.inProcess,
+		schema:         schemaOptions(opts),
+	}
+
+	// Steps see the same clients that are returned to the caller. On failure,
+	// the deferred rollback closes them and drops what this call created.
+	if err := runSetupSteps(ctx, opts, clients); err != nil {
+		return nil, err
+	}
+	return clients, nil
 }
 
 func rollbackCreatedResources(instanceCli *instance.InstanceAdminClient, dbCli *database.DatabaseAdminClient, opts *emulatorOptions, resources createdSchemaResources) error {
@@ -15,13 +15,20 @@ func TestCreateDatabaseRequestIncludesFileDescriptorSet(t *testing.T) {
 		t.Fatalf("applyOptions: %v", err)
 	}
 
-	req := createDatabaseRequest(opts, opts.InstancePath(), "CREATE DATABASE `test`")
+	req := createDatabaseRequest(opts, opts.InstancePath(), "CREATE DATABASE `test`", createDatabaseExtraStatements(opts))
 	if !bytes.Equal(req.ProtoDescriptors, raw) {
 		t.Fatalf("ProtoDescriptors = %q, want %q", req.ProtoDescriptors, raw)
 	}
 	if len(req.ExtraStatements) != 1 {
 		t.Fatalf("len(ExtraStatements) = %d, want 1", len(req.ExtraStatements))
 	}
+
+	// Without the statements, as when locating a failing statement, the
+	// descriptors are sent with UpdateDatabaseDdl instead.
+	req = createDatabaseRequest(opts, opts.InstancePath(), "CREATE DATABASE `test`", nil)
+	if req.ProtoDescriptors != nil {
+		t.Fatalf("ProtoDescriptors without statements = %q, want nil", req.ProtoDescriptors)
+	}
 }
 
 func TestUpdateDatabaseDdlRequestIncludesFileDescriptorSet(t *testing.T) {
@@ -34,7 +41,7 @@ func TestUpdateDatabaseDdlRequestIncludesFileDescriptorSet(t *testing.T) {
 		t.Fatalf("applyOptions: %v", err)
 	}
 
-	req := updateDatabaseDdlRequest(opts)
+	req := updateDatabaseDdlRequest(opts, opts.setupDDLs)
 	if !bytes.Equal(req.ProtoDescriptors, raw) {
 		t.Fatalf("ProtoDescriptors = %q, want %q", req.ProtoDescriptors, raw)
 	}
@@ -49,7 +56,7 @@ func TestCreateDatabaseRequestOmitsUnsetFileDescriptorSet(t *testing.T) {
 		t.Fatalf("applyOptions: %v", err)
 	}
 
-	req := createDatabaseRequest(opts, opts.InstancePath(), "CREATE DATABASE `test`")
+	req := createDatabaseRequest(opts, opts.InstancePath(), "CREATE DATABASE `test`", createDatabaseExtraStatements(opts))
 	if req.ProtoDescriptors != nil {
 		t.Fatalf("ProtoDescriptors = %q, want nil", req.ProtoDescriptors)
 	}
@@ -61,7 +68,7 @@ func TestUpdateDatabaseDdlRequestOmitsUnsetFileDescriptorSet(t *testing.T) {
 		t.Fatalf("applyOptions: %v", err)
 	}
 
-	req := updateDatabaseDdlRequest(opts)
+	req := updateDatabaseDdlRequest(opts, opts.setupDDLs)
 	if req.ProtoDescriptors != nil {
 		t.Fatalf("ProtoDescriptors = %q, want nil", req.ProtoDescriptors)
 	}
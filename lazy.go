@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 type lazyRuntimeState struct {
@@ -76,6 +77,14 @@ type LazyRuntime struct {
 	opts    []Option
 
 	attachedEndpoint *Endpoint
+
+	// policy is set by NewLazyRuntimeWithPolicy, which stores the chosen
+	// candidate in decision.
+	policy   *RuntimePolicy
+	decision atomic.Pointer[RuntimeDecision]
+
+	// shared is set by NewSharedLazyRuntime.
+	shared *sharedRuntimeState
 }
 
 func (*LazyRuntime) spanemuboostRuntime() {}
@@ -94,6 +103,12 @@ func (lr *LazyRuntime) get(ctx context.Context) (runtimeInstance, error) {
 		if lr.attachedEndpoint != nil {
 			return NewAttachedRuntime(*lr.attachedEndpoint, lr.opts...)
 		}
+		if lr.policy != nil {
+			return lr.startWithPolicy(ctx)
+		}
+		if lr.shared != nil {
+			return lr.shared.acquire(ctx, lr.backend, lr.opts)
+		}
 		runtime, err := Run(ctx, lr.backend, lr.opts...)
 		if err != nil {
 			return nil, err
@@ -128,5 +143,10 @@ func (lr *LazyRuntime) Close() error {
 	if lr == nil {
 		return nil
 	}
+	if lr.shared != nil {
+		// Wait for in-progress initialization, which takes the lease.
+		lr.state.once.Do(func() {})
+		return lr.shared.release(lr.state.close)
+	}
 	return lr.state.close()
 }
@@ -0,0 +1,84 @@
+package spanemuboost
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"testing"
+)
+
+// MigrationUpgradeConfig configures [RunMigrationUpgrades].
+type MigrationUpgradeConfig struct {
+	// FS and Dir are the migration source, as given to [WithMigrations].
+	FS  fs.FS
+	Dir string
+	// Fixtures returns the options that load the data of a database at
+	// version, such as [WithSetupFixtures] for a per-version fixture
+	// directory. It may be nil, and may return nil for versions without
+	// fixtures.
+	Fixtures func(version int) []Option
+	// Verify is called after each upgrade. It may be nil.
+	Verify func(t *testing.T, upgrade MigrationUpgrade)
+	// Options are applied to every database before the migrations and
+	// fixtures.
+	Options []Option
+}
+
+// MigrationUpgrade is one upgrade step run by [RunMigrationUpgrades].
+type MigrationUpgrade struct {
+	// From is the version the database was at, with its fixtures loaded.
+	From int
+	// To is the version of the migration that was applied.
+	To int
+	// Clients is connected to the upgraded database.
+	Clients *Clients
+}
+
+// RunMigrationUpgrades tests that each migration applies cleanly to a
+// populated database at the previous version, not just to an empty one.
+//
+// For each migration after the first, it runs a subtest named
+// "<from>_to_<to>" that creates a fresh database on runtime, applies the
+// migrations up to the previous version, loads the fixtures of that version,
+// applies the migration, and calls cfg.Verify. The databases are dropped when
+// the subtests finish.
+func RunMigrationUpgrades(t *testing.T, runtime RuntimeHandle, cfg MigrationUpgradeConfig) {
+	t.Helper()
+
+	migrations, err := readMigrations(cfg.FS, cfg.Dir, LatestMigrationVersion)
+	if err != nil {
+		t.Fatalf("spanemuboost: RunMigrationUpgrades: %v", err)
+	}
+	if len(migrations) < 2 {
+		t.Fatalf("spanemuboost: RunMigrationUpgrades: %q has %d migrations, want at least 2", cfg.Dir, len(migrations))
+	}
+
+	for i := 1; i < len(migrations); i++ {
+		from, to := migrations[i-1].version, migrations[i]
+		t.Run(fmt.Sprintf("%d_to_%d", from, to.version), func(t *testing.T) {
+			options := slices.Concat(
+				[]Option{ForceSchemaTeardown()},
+				cfg.Options,
+				[]Option{WithRandomDatabaseID(), WithMigrations(cfg.FS, cfg.Dir, from)},
+			)
+			if cfg.Fixtures != nil {
+				options = append(options, cfg.Fixtures(from)...)
+			}
+			clients := SetupClients(t, runtime, options...)
+
+			opts := *clients.schemaOrDefault()
+			opts.databaseID = clients.DatabaseID
+			ddls, sources, err := splitSQLFiles([]setupFile{to.file}, &opts)
+			if err != nil {
+				t.Fatalf("spanemuboost: migration %d: %v", to.version, err)
+			}
+			if err := updateDDLStatements(t.Context(), &opts, clients.DatabaseClient, ddls, sources); err != nil {
+				t.Fatalf("spanemuboost: migration %d failed on a populated database at version %d: %v", to.version, from, err)
+			}
+
+			if cfg.Verify != nil {
+				cfg.Verify(t, MigrationUpgrade{From: from, To: to.version, Clients: clients})
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package spanemuboost
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunMigrationUpgrades(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	fixtures := fstest.MapFS{
+		"fixtures/1/tbl.csv": {Data: []byte("pk\na\nb\n")},
+		"fixtures/2/tbl.csv": {Data: []byte("pk,col\na,1\n")},
+	}
+
+	var got []string
+	RunMigrationUpgrades(t, emu, MigrationUpgradeConfig{
+		FS:  testMigrationsFS(),
+		Dir: "migrations",
+		Fixtures: func(version int) []Option {
+			return []Option{WithSetupFixtures(fixtures, fmt.Sprintf("fixtures/%d/*", version))}
+		},
+		Verify: func(t *testing.T, upgrade MigrationUpgrade) {
+			var n int64
+			err := upgrade.Clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT COUNT(*) FROM tbl")).Do(func(r *spanner.Row) error {
+				return r.Column(0, &n)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, fmt.Sprintf("%d->%d: %d rows", upgrade.From, upgrade.To, n))
+		},
+	})
+
+	want := []string{"1->2: 2 rows", "2->10: 1 rows"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("upgrades mismatch (-want +got):\n%s", diff)
+	}
+}
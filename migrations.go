@@ -0,0 +1,150 @@
+package spanemuboost
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+)
+
+// LatestMigrationVersion selects every migration found by [WithMigrations].
+const LatestMigrationVersion = -1
+
+// migrationFileNamePattern matches <version>_<name>.sql and <version>_<name>.up.sql.
+var migrationFileNamePattern = regexp.MustCompile(`^([0-9]+)_.+\.sql$`)
+
+// migration is one numbered migration file selected by WithMigrations.
+// file is split at option finalization; ddls and sources are set afterwards.
+type migration struct {
+	version int
+	file    setupFile
+	ddls    []string
+	sources []string
+}
+
+// WithMigrations applies numbered migration files in dir of fsys during
+// database bootstrap.
+//
+// Migration files are named <version>_<name>.sql, e.g. 0001_init.sql or
+// 0002_add_index.up.sql, and are applied in ascending version order. Files
+// ending in .down.sql, files without the .sql extension, and subdirectories
+// are ignored. Versions must be positive, and two files with the same version
+// are an error.
+//
+// Only migrations with a version less than or equal to targetVersion are
+// applied, so that a test can observe the schema at a given version. Use
+// [LatestMigrationVersion] to apply every migration, or 0 to apply none.
+// Other values must match the version of an existing migration file.
+//
+// Each migration file is split like [WithSetupDDLFiles] and applied as a
+// separate UpdateDatabaseDdl operation. When the database is created by
+// spanemuboost, migrations run right after creation and [WithSetupDDLs] are
+// applied after the migrations. When database creation is disabled,
+// migrations are applied to the existing database in the same way.
+//
+// Calling this multiple times replaces the previous value.
+func WithMigrations(fsys fs.FS, dir string, targetVersion int) Option {
+	return func(opts *emulatorOptions) error {
+		migrations, err := readMigrations(fsys, dir, targetVersion)
+		if err != nil {
+			return fmt.Errorf("WithMigrations: %w", err)
+		}
+		opts.migrations = migrations
+		return nil
+	}
+}
+
+func readMigrations(fsys fs.FS, dir string, targetVersion int) ([]migration, error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("fs.FS is nil")
+	}
+	if targetVersion < LatestMigrationVersion {
+		return nil, fmt.Errorf("targetVersion must be >= 0 or LatestMigrationVersion, got %d", targetVersion)
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+		m := migrationFileNamePattern.FindStringSubmatch(name)
+		if m == nil {
+			return nil, fmt.Errorf("migration file %q must be named <version>_<name>.sql", path.Join(dir, name))
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration file %q: invalid version: %w", path.Join(dir, name), err)
+		}
+		if version == 0 {
+			return nil, fmt.Errorf("migration file %q: version must be > 0, because target version 0 applies no migrations", path.Join(dir, name))
+		}
+		all = append(all, migration{version: version, file: setupFile{name: path.Join(dir, name)}})
+	}
+	slices.SortFunc(all, func(a, b migration) int { return cmp.Compare(a.version, b.version) })
+	for i := 1; i < len(all); i++ {
+		if all[i].version == all[i-1].version {
+			return nil, fmt.Errorf("migration files %q and %q have the same version %d", all[i-1].file.name, all[i].file.name, all[i].version)
+		}
+	}
+
+	if targetVersion > 0 && !slices.ContainsFunc(all, func(m migration) bool { return m.version == targetVersion }) {
+		return nil, fmt.Errorf("no migration file in %q has target version %d", dir, targetVersion)
+	}
+
+	var selected []migration
+	for _, m := range all {
+		if targetVersion != LatestMigrationVersion && m.version > targetVersion {
+			break
+		}
+		content, err := fs.ReadFile(fsys, m.file.name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", m.file.name, err)
+		}
+		m.file.content = string(content)
+		selected = append(selected, m)
+	}
+	return selected, nil
+}
+
+// resolveMigrations splits pending migration files into DDL statements.
+// It is idempotent so that options can be finalized again when inherited.
+func resolveMigrations(opts *emulatorOptions) error {
+	for i := range opts.migrations {
+		m := &opts.migrations[i]
+		if m.ddls != nil {
+			continue
+		}
+		ddls, sources, err := splitSQLFiles([]setupFile{m.file}, opts)
+		if err != nil {
+			return fmt.Errorf("WithMigrations: %w", err)
+		}
+		if len(ddls) == 0 {
+			return fmt.Errorf("WithMigrations: migration file %q contains no statements", m.file.name)
+		}
+		m.ddls = ddls
+		m.sources = sources
+	}
+	return nil
+}
+
+// applyMigrations applies each migration as a separate UpdateDatabaseDdl operation.
+func applyMigrations(ctx context.Context, opts *emulatorOptions, dbCli *database.DatabaseAdminClient) error {
+	for _, m := range opts.migrations {
+		if err := updateDDLStatements(ctx, opts, dbCli, m.ddls, m.sources); err != nil {
+			return fmt.Errorf("migration %d: %w", m.version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package spanemuboost
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testMigrationsFS() fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/0001_init.sql": {Data: []byte("CREATE TABLE tbl (\n  pk STRING(MAX),\n) PRIMARY KEY (pk);\n")},
+		"migrations/0002_add_col.up.sql": {Data: []byte("ALTER TABLE tbl ADD COLUMN col INT64;\n" +
+			"CREATE INDEX idx_col ON tbl (col);\n")},
+		"migrations/0002_add_col.down.sql": {Data: []byte("DROP INDEX idx_col; ALTER TABLE tbl DROP COLUMN col;")},
+		"migrations/0010_other.sql":        {Data: []byte("CREATE TABLE other (pk INT64) PRIMARY KEY (pk)")},
+		"migrations/README.md":             {Data: []byte("not a migration")},
+		"migrations/archive/0003_old.sql":  {Data: []byte("CREATE TABLE old (pk INT64) PRIMARY KEY (pk)")},
+	}
+}
+
+func TestWithMigrationsSelectsTargetVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       int
+		wantVersions []int
+	}{
+		{name: "latest", target: LatestMigrationVersion, wantVersions: []int{1, 2, 10}},
+		{name: "intermediate", target: 2, wantVersions: []int{1, 2}},
+		{name: "first", target: 1, wantVersions: []int{1}},
+		{name: "none", target: 0, wantVersions: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := applyOptions(WithMigrations(testMigrationsFS(), "migrations", tt.target))
+			if err != nil {
+				t.Fatalf("applyOptions: %v", err)
+			}
+			var got []int
+			for _, m := range opts.migrations {
+				got = append(got, m.version)
+			}
+			if diff := cmp.Diff(tt.wantVersions, got); diff != "" {
+				t.Fatalf("migration versions mismatch (-want +got):\n%s", diff)
+			}
+			if got, want := opts.hasSetupDDLWork(), len(tt.wantVersions) > 0; got != want {
+				t.Fatalf("hasSetupDDLWork() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithMigrationsSplitsEachFile(t *testing.T) {
+	opts, err := applyOptions(WithMigrations(testMigrationsFS(), "migrations", 2))
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if len(opts.migrations) != 2 {
+		t.Fatalf("len(migrations) = %d, want 2", len(opts.migrations))
+	}
+
+	m := opts.migrations[1]
+	wantDDLs := []string{
+		"ALTER TABLE tbl ADD COLUMN col INT64",
+		"CREATE INDEX idx_col ON tbl (col)",
+	}
+	if diff := cmp.Diff(wantDDLs, m.ddls); diff != "" {
+		t.Fatalf("ddls mismatch (-want +got):\n%s", diff)
+	}
+	wantSources := []string{
+		"migrations/0002_add_col.up.sql:1",
+		"migrations/0002_add_col.up.sql:2",
+	}
+	if diff := cmp.Diff(wantSources, m.sources); diff != "" {
+		t.Fatalf("sources mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithMigrationsReportsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fsys   fstest.MapFS
+		target int
+		want   string
+	}{
+		{
+			name:   "missing target version",
+			fsys:   testMigrationsFS(),
+			target: 3,
+			want:   `no migration file in "migrations" has target version 3`,
+		},
+		{
+			name:   "invalid target version",
+			fsys:   testMigrationsFS(),
+			target: -2,
+			want:   "targetVersion must be >= 0 or LatestMigrationVersion, got -2",
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"migrations/1_a.sql":  {Data: []byte("CREATE TABLE a (pk INT64) PRIMARY KEY (pk)")},
+				"migrations/01_b.sql": {Data: []byte("CREATE TABLE b (pk INT64) PRIMARY KEY (pk)")},
+			},
+			target: LatestMigrationVersion,
+			want:   `migration files "migrations/01_b.sql" and "migrations/1_a.sql" have the same version 1`,
+		},
+		{
+			name: "version zero",
+			fsys: fstest.MapFS{
+				"migrations/0000_init.sql": {Data: []byte("CREATE TABLE a (pk INT64) PRIMARY KEY (pk)")},
+			},
+			target: 0,
+			want:   `migration file "migrations/0000_init.sql": version must be > 0`,
+		},
+		{
+			name: "unversioned file",
+			fsys: fstest.MapFS{
+				"migrations/init.sql": {Data: []byte("CREATE TABLE a (pk INT64) PRIMARY KEY (pk)")},
+			},
+			target: LatestMigrationVersion,
+			want:   `migration file "migrations/init.sql" must be named <version>_<name>.sql`,
+		},
+		{
+			name: "empty migration",
+			fsys: fstest.MapFS{
+				"migrations/0001_empty.sql": {Data: []byte("-- nothing yet\n")},
+			},
+			target: LatestMigrationVersion,
+			want:   `migration file "migrations/0001_empty.sql" contains no statements`,
+		},
+		{
+			name:   "missing directory",
+			fsys:   fstest.MapFS{},
+			target: LatestMigrationVersion,
+			want:   "WithMigrations: open migrations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyOptions(WithMigrations(tt.fsys, "migrations", tt.target))
+			if err == nil {
+				t.Fatal("applyOptions() error = nil, want non-nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("applyOptions() error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateDatabaseRequestDefersSetupDDLsWithMigrations(t *testing.T) {
+	opts, err := applyOptions(
+		WithMigrations(testMigrationsFS(), "migrations", LatestMigrationVersion),
+		WithSetupDDLs([]string{"CREATE TABLE extra (pk INT64) PRIMARY KEY (pk)"}),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+
+	req := createDatabaseRequest(opts, opts.InstancePath(), "CREATE DATABASE `test`", createDatabaseExtraStatements(opts))
+	if len(req.ExtraStatements) != 0 {
+		t.Fatalf("ExtraStatements = %q, want none", req.ExtraStatements)
+	}
+}
+
+func TestWithMigrationsSatisfiesFileDescriptorSetRequirement(t *testing.T) {
+	_, err := applyOptions(
+		DisableAutoConfig(),
+		WithMigrations(testMigrationsFS(), "migrations", 1),
+		WithSetupRawFileDescriptorSet([]byte("proto-descriptors")),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions() error = %v, want nil", err)
+	}
+}
+
+func TestWithMigrations(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+
+	columns := func(t *testing.T, clients *Clients) []string {
+		t.Helper()
+		stmt := spanner.NewStatement(`SELECT CONCAT(TABLE_NAME, ".", COLUMN_NAME) FROM INFORMATION_SCHEMA.COLUMNS
+WHERE TABLE_SCHEMA = "" ORDER BY TABLE_NAME, ORDINAL_POSITION`)
+		var got []string
+		err := clients.Client.Single().Query(t.Context(), stmt).Do(func(r *spanner.Row) error {
+			var column string
+			if err := r.Column(0, &column); err != nil {
+				return err
+			}
+			got = append(got, column)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("target version", func(t *testing.T) {
+		clients := SetupClients(t, emu,
+			WithRandomDatabaseID(),
+			WithMigrations(testMigrationsFS(), "migrations", 1),
+		)
+		if diff := cmp.Diff([]string{"tbl.pk"}, columns(t, clients)); diff != "" {
+			t.Fatalf("columns mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("latest with setup DDLs", func(t *testing.T) {
+		clients := SetupClients(t, emu,
+			WithRandomDatabaseID(),
+			WithMigrations(testMigrationsFS(), "migrations", LatestMigrationVersion),
+			WithSetupDDLs([]string{"ALTER TABLE other ADD COLUMN note STRING(MAX)"}),
+		)
+		want := []string{"other.pk", "other.note", "tbl.pk", "tbl.col"}
+		if diff := cmp.Diff(want, columns(t, clients)); diff != "" {
+			t.Fatalf("columns mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("failure location", func(t *testing.T) {
+		fsys := testMigrationsFS()
+		fsys["migrations/0011_broken.sql"] = &fstest.MapFile{Data: []byte("\nALTER TABLE missing ADD COLUMN c INT64")}
+		_, err := OpenClients(t.Context(), emu,
+			WithRandomDatabaseID(),
+			WithMigrations(fsys, "migrations", LatestMigrationVersion),
+		)
+		if err == nil {
+			t.Fatal("OpenClients() error = nil, want migration failure")
+		}
+		if want := "migration 11: failed to apply DDL statement 0 at migrations/0011_broken.sql:2"; !strings.Contains(err.Error(), want) {
+			t.Fatalf("OpenClients() error = %q, want substring %q", err, want)
+		}
+		var stmtErr *SetupStatementError
+		if !errors.As(err, &stmtErr) {
+			t.Fatalf("OpenClients() error = %v, want *SetupStatementError", err)
+		}
+		if stmtErr.Stage != SetupStageDDL || stmtErr.Statement != "ALTER TABLE missing ADD COLUMN c INT64" {
+			t.Fatalf("SetupStatementError = %+v, want DDL stage with the failing statement", stmtErr)
+		}
+	})
+}
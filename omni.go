@@ -88,6 +88,9 @@ func (o *omniRuntime) Close() error {
 		return nil
 	}
 	return o.closeState.close(func() error {
+		if o.opts != nil {
+			o.opts.schemaTemplates.close()
+		}
 		if o.container == nil {
 			return nil
 		}
@@ -238,6 +241,12 @@ func finalizeOmniOptions(opts *emulatorOptions) (*emulatorOptions, error) {
 	if err := applyContainerProviderEnv(opts); err != nil {
 		return nil, err
 	}
+	if err := resolveSetupFiles(opts); err != nil {
+		return nil, err
+	}
+	if err := filterSetupFileDescriptorSet(opts); err != nil {
+		return nil, err
+	}
 	if err := validateSetupFileDescriptorSet(opts); err != nil {
 		return nil, err
 	}
@@ -264,6 +273,7 @@ func startOmni(ctx context.Context, opts *emulatorOptions) (*omniRuntime, error)
 		return nil, err
 	}
 
+	enableSchemaTemplates(opts)
 	return &omniRuntime{
 		container: container,
 		opts:      opts,
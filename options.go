@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand/v2"
 	"os"
+	"reflect"
 	"strings"
 
 	"cloud.google.com/go/spanner"
@@ -13,11 +14,13 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	"google.golang.org/api/option"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type emulatorOptions struct {
 	emulatorImage                     string
+	emulatorBinary                    string // gateway_main for BackendEmulatorBinary
 	projectID, instanceID, databaseID string
 
 	randomProjectID, randomInstanceID, randomDatabaseID bool
@@ -28,19 +31,47 @@ type emulatorOptions struct {
 	reuseExistingDatabase    bool
 	schemaTeardown           *bool
 
-	databaseDialect        databasepb.DatabaseDialect
-	setupDDLs              []string
-	setupFileDescriptorSet []byte
-	setupDMLs              []spanner.Statement
-	clientConfig           *spanner.ClientConfig // nil until finalizeOptions; guaranteed non-nil after
-	containerCustomizers   []testcontainers.ContainerCustomizer
-	containerProviderSet   bool
-	clientOptionsForClient []option.ClientOption
+	databaseDialect         databasepb.DatabaseDialect
+	setupDDLs               []string
+	setupDDLSources         []string
+	setupDDLFiles           []setupFile
+	setupFileDescriptorSet  []byte
+	setupProtoTypeFiles     []protoreflect.FileDescriptor // collected by WithSetupProtoMessages and WithSetupProtoEnums
+	filterFileDescriptorSet bool
+	setupDMLs               []spanner.Statement
+	setupDMLSources         []string
+	setupDMLFiles           []setupFile
+	migrations              []migration
+	setupFixtures           []fixture
+	setupMutations          []*spanner.Mutation
+	// setupDMLBoundaries and setupMutationBoundaries hold ascending indexes
+	// at which an explicit batch starts a new transaction.
+	setupDMLBoundaries      []int
+	setupMutationBoundaries []int
+	setupBatchSize          int
+	setupSteps              []Step
+	schemaTemplatePrewarm   int
+	maxDatabasesPerInstance int
+	forkData                bool
+	copyRows                bool
+	copyRowLimit            int
+	copyRowFunc             CopyRowFunc
+	copiedRows              []*spanner.Mutation   // rows read by Fork and CopyDatabase, written before any setup data
+	requireNewDatabase      bool                  // set by CopyDatabase; an existing database fails the bootstrap
+	schemaTemplates         *schemaTemplateCache  // set on runtimes by enableSchemaTemplates and inherited by OpenClients
+	clientConfig            *spanner.ClientConfig // nil until finalizeOptions; guaranteed non-nil after
+	containerCustomizers    []testcontainers.ContainerCustomizer
+	containerProviderSet    bool
+	clientOptionsForClient  []option.ClientOption
 
 	// gatewayFlags accumulates extra arguments appended to the emulator
 	// gateway_main command line. They are emulator-specific; finalizeOmniOptions
 	// rejects them unless backend guardrails are disabled.
 	gatewayFlags []string
+
+	// inProcess is set by applyInProcessOptionsWithBase, so that [Clients]
+	// can reject helpers that spannertest cannot serve.
+	inProcess bool
 }
 
 // Option configures spanemuboost runtime bootstrap behavior.
@@ -133,6 +164,7 @@ func WithMaxDatabasesPerInstance(n int) Option {
 			return fmt.Errorf("WithMaxDatabasesPerInstance: n must be > 0, got %d", n)
 		}
 		opts.gatewayFlags = append(opts.gatewayFlags, fmt.Sprintf("--override_max_databases_per_instance=%d", n))
+		opts.maxDatabasesPerInstance = n
 		return nil
 	}
 }
@@ -329,9 +361,12 @@ func WithClientConfig(config spanner.ClientConfig) Option {
 
 // WithSetupDDLs sets DDLs to be executed.
 // Calling this multiple times replaces the previous value.
+// This is mutually exclusive with [WithSetupDDLFiles]; the last one called wins.
 func WithSetupDDLs(ddls []string) Option {
 	return func(opts *emulatorOptions) error {
 		opts.setupDDLs = ddls
+		opts.setupDDLSources = nil
+		opts.setupDDLFiles = nil
 		return nil
 	}
 }
@@ -340,7 +375,8 @@ func WithSetupDDLs(ddls []string) Option {
 // statements in [WithSetupDDLs]. Use this option together with setup DDLs that
 // reference proto bundles; the value is serialized for CreateDatabase and
 // UpdateDatabaseDdl requests at bootstrap time.
-// Calling this multiple times replaces the previous value.
+// Calling this multiple times replaces the previous value, including
+// descriptors collected by [WithSetupProtoMessages] and [WithSetupProtoEnums].
 func WithSetupFileDescriptorSet(fds *descriptorpb.FileDescriptorSet) Option {
 	var raw []byte
 	var err error
@@ -352,6 +388,7 @@ func WithSetupFileDescriptorSet(fds *descriptorpb.FileDescriptorSet) Option {
 			return fmt.Errorf("marshal file descriptor set: %w", err)
 		}
 		opts.setupFileDescriptorSet = raw
+		opts.setupProtoTypeFiles = nil
 		return nil
 	}
 }
@@ -366,13 +403,15 @@ func WithSetupRawFileDescriptorSet(raw []byte) Option {
 	cloned := bytes.Clone(raw)
 	return func(opts *emulatorOptions) error {
 		opts.setupFileDescriptorSet = cloned
+		opts.setupProtoTypeFiles = nil
 		return nil
 	}
 }
 
 // WithSetupRawDMLs sets string DMLs to be executed.
 // Calling this multiple times replaces the previous value.
-// This is mutually exclusive with WithSetupDMLs; the last one called wins.
+// This is mutually exclusive with WithSetupDMLs, [WithSetupDMLBatches], and
+// [WithSetupDMLFiles]; the last one called wins.
 func WithSetupRawDMLs(rawDMLs []string) Option {
 	return func(opts *emulatorOptions) error {
 		dmlStmts := make([]spanner.Statement, 0, len(rawDMLs))
@@ -381,16 +420,60 @@ func WithSetupRawDMLs(rawDMLs []string) Option {
 		}
 
 		opts.setupDMLs = dmlStmts
+		opts.setupDMLBoundaries = nil
+		opts.setupDMLSources = nil
+		opts.setupDMLFiles = nil
 		return nil
 	}
 }
 
 // WithSetupDMLs sets DMLs in spanner.Statement to be executed.
 // Calling this multiple times replaces the previous value.
-// This is mutually exclusive with WithSetupRawDMLs; the last one called wins.
+// This is mutually exclusive with WithSetupRawDMLs, [WithSetupDMLBatches], and
+// [WithSetupDMLFiles]; the last one called wins.
 func WithSetupDMLs(dmls []spanner.Statement) Option {
 	return func(opts *emulatorOptions) error {
 		opts.setupDMLs = dmls
+		opts.setupDMLBoundaries = nil
+		opts.setupDMLSources = nil
+		opts.setupDMLFiles = nil
+		return nil
+	}
+}
+
+// WithSetupMutations adds mutations to be applied after setup fixtures and
+// before setup DMLs. Mutations are applied in transactions of up to
+// [WithSetupBatchSize] mutations.
+// Calling this multiple times appends to the previous value, as do
+// [WithSetupStructs] and [WithSetupMutationBatches].
+func WithSetupMutations(mutations []*spanner.Mutation) Option {
+	return func(opts *emulatorOptions) error {
+		opts.setupMutations = append(opts.setupMutations, mutations...)
+		return nil
+	}
+}
+
+// WithSetupStructs adds an insert mutation into table for each element of
+// rows, which must be a slice or array of structs or struct pointers.
+// Elements are converted with [spanner.InsertStruct], so fields are mapped
+// using `spanner:` struct tags.
+// Calling this multiple times appends to the previous value, as do
+// [WithSetupMutations] and [WithSetupMutationBatches].
+func WithSetupStructs(table string, rows any) Option {
+	return func(opts *emulatorOptions) error {
+		v := reflect.ValueOf(rows)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("WithSetupStructs: rows must be a slice or array, got %T", rows)
+		}
+		mutations := make([]*spanner.Mutation, 0, v.Len())
+		for i := range v.Len() {
+			m, err := spanner.InsertStruct(table, v.Index(i).Interface())
+			if err != nil {
+				return fmt.Errorf("WithSetupStructs: %s row %d: %w", table, i, err)
+			}
+			mutations = append(mutations, m)
+		}
+		opts.setupMutations = append(opts.setupMutations, mutations...)
 		return nil
 	}
 }
@@ -476,8 +559,12 @@ func (o *emulatorOptions) shouldDropDatabase() bool {
 	return o.shouldDropResource(o.disableCreateDatabase, o.randomDatabaseID)
 }
 
+func (o *emulatorOptions) hasSetupDataWork() bool {
+	return len(o.copiedRows) > 0 || len(o.setupFixtures) > 0 || len(o.setupMutations) > 0 || len(o.setupDMLs) > 0
+}
+
 func (o *emulatorOptions) hasSetupDDLWork() bool {
-	return len(o.setupDDLs) > 0
+	return len(o.setupDDLs) > 0 || len(o.migrations) > 0
 }
 
 // shouldDropResource returns whether a resource should be dropped on Close.
@@ -575,6 +662,14 @@ func finalizeOptions(opts *emulatorOptions) (*emulatorOptions, error) {
 		return nil, err
 	}
 
+	if err := resolveSetupFiles(opts); err != nil {
+		return nil, err
+	}
+
+	if err := filterSetupFileDescriptorSet(opts); err != nil {
+		return nil, err
+	}
+
 	if err := validateSetupFileDescriptorSet(opts); err != nil {
 		return nil, err
 	}
@@ -583,13 +678,13 @@ func finalizeOptions(opts *emulatorOptions) (*emulatorOptions, error) {
 }
 
 func validateSetupFileDescriptorSet(opts *emulatorOptions) error {
-	if len(opts.setupFileDescriptorSet) == 0 || len(opts.setupDDLs) > 0 {
+	if len(opts.setupFileDescriptorSet) == 0 || opts.hasSetupDDLWork() || opts.hasDDLSetupStep() {
 		return nil
 	}
 	if !opts.disableCreateDatabase {
 		return nil
 	}
-	return fmt.Errorf("setup file descriptor set requires WithSetupDDLs when database auto-creation is disabled")
+	return fmt.Errorf("setup file descriptor set requires WithSetupDDLs, WithMigrations, or a DDLStep when database auto-creation is disabled")
 }
 
 const (
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"cloud.google.com/go/spanner"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -370,3 +371,47 @@ func TestHasSetupDDLWork(t *testing.T) {
 		}
 	})
 }
+
+func TestWithSetupStructs(t *testing.T) {
+	type singer struct {
+		SingerID int64  `spanner:"SingerId"`
+		Name     string `spanner:"Name"`
+	}
+
+	opts, err := applyOptions(
+		WithSetupStructs("Singers", []singer{{1, "Alice"}, {2, "Bob"}}),
+		WithSetupMutations([]*spanner.Mutation{spanner.Delete("Singers", spanner.Key{3})}),
+		WithSetupStructs("Singers", [1]*singer{{4, "Carol"}}),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if got, want := len(opts.setupMutations), 4; got != want {
+		t.Fatalf("len(setupMutations) = %d, want %d", got, want)
+	}
+	if !opts.hasSetupDataWork() {
+		t.Fatal("hasSetupDataWork() = false, want true")
+	}
+}
+
+func TestWithSetupStructsRejectsInvalidRows(t *testing.T) {
+	tests := []struct {
+		name string
+		rows any
+		want string
+	}{
+		{name: "not a slice", rows: struct{}{}, want: "WithSetupStructs: rows must be a slice or array, got struct {}"},
+		{name: "not a struct", rows: []int{1}, want: "WithSetupStructs: Singers row 0:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyOptions(WithSetupStructs("Singers", tt.rows))
+			if err == nil {
+				t.Fatal("applyOptions() error = nil, want non-nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("applyOptions() error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
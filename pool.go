@@ -0,0 +1,215 @@
+package spanemuboost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// defaultMaxDatabasesPerInstance is the emulator's default limit on databases
+// per instance. [WithMaxDatabasesPerInstance] only raises it.
+const defaultMaxDatabasesPerInstance = 100
+
+// DatabasePool keeps databases prepared ahead of time on a runtime so that
+// tests can take a fully bootstrapped database without waiting for it.
+// Use [NewDatabasePool] or [SetupDatabasePool] to create one.
+//
+// Each pooled database is created with [WithRandomDatabaseID] and the pool's
+// options, including setup DDLs, fixtures, mutations, DMLs, and steps, by
+// background goroutines. A database is used by one caller only and is dropped
+// when it is released; it is never handed out twice.
+//
+// Database creation and drops go through the same per-instance serialization
+// as [OpenClients]. The pool never holds more databases than the runtime's
+// per-instance limit ([WithMaxDatabasesPerInstance], or the emulator default
+// of 100) at once; when the limit is reached, preparing further databases
+// waits for released databases to be dropped. Databases created outside the
+// pool are not counted.
+type DatabasePool struct {
+	runtime runtimeInstance
+	options []Option
+	ready   chan *Clients
+	live    *semaphore.Weighted
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	fillers sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	releases sync.WaitGroup
+
+	failed   chan struct{}
+	failErr  error
+	failOnce sync.Once
+
+	closeState closeState
+}
+
+// NewDatabasePool starts preparing size databases on runtime in the
+// background and returns the pool. A lazy runtime is started if needed.
+// options are applied to every pooled database as for [OpenClients]; they
+// must not set a fixed database ID, for example with [WithoutRandomDatabaseID]
+// and [WithDatabaseID].
+//
+// Call [DatabasePool.Close] to stop preparing databases and drop the prepared
+// ones. In tests, prefer [SetupDatabasePool].
+func NewDatabasePool(ctx context.Context, runtime RuntimeHandle, size int, options ...Option) (*DatabasePool, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("NewDatabasePool: size must be > 0, got %d", size)
+	}
+	r, err := resolveRuntime(ctx, runtime)
+	if err != nil {
+		return nil, err
+	}
+	options = slices.Concat([]Option{WithRandomDatabaseID()}, options)
+	opts, err := r.inheritedOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+	// Every filler would otherwise target the same database, and pooled
+	// clients drop their database when closed.
+	if !opts.randomDatabaseID {
+		return nil, fmt.Errorf("NewDatabasePool: options must not set a fixed database ID, got %q", opts.databaseID)
+	}
+	limit := max(opts.maxDatabasesPerInstance, defaultMaxDatabasesPerInstance)
+	if size > limit {
+		return nil, fmt.Errorf("NewDatabasePool: size %d exceeds the limit of %d databases per instance", size, limit)
+	}
+
+	poolCtx, cancel := context.WithCancel(context.Background())
+	p := &DatabasePool{
+		runtime: r,
+		options: options,
+		ready:   make(chan *Clients),
+		live:    semaphore.NewWeighted(int64(limit)),
+		ctx:     poolCtx,
+		cancel:  cancel,
+		failed:  make(chan struct{}),
+	}
+	p.fillers.Add(size)
+	for range size {
+		go p.fill()
+	}
+	return p, nil
+}
+
+// SetupDatabasePool is like [NewDatabasePool] and registers
+// [DatabasePool.Close] via [testing.TB.Cleanup]. It calls [testing.TB.Fatal]
+// on error.
+func SetupDatabasePool(tb testing.TB, runtime RuntimeHandle, size int, options ...Option) *DatabasePool {
+	tb.Helper()
+	return setupWithCleanup(tb, func(ctx context.Context) (*DatabasePool, error) {
+		return NewDatabasePool(ctx, runtime, size, options...)
+	}, "database pool")
+}
+
+// fill prepares one database at a time and waits until it is taken.
+func (p *DatabasePool) fill() {
+	defer p.fillers.Done()
+	for {
+		if err := p.live.Acquire(p.ctx, 1); err != nil {
+			return
+		}
+		clients, err := OpenClients(p.ctx, p.runtime, p.options...)
+		if err != nil {
+			p.live.Release(1)
+			if p.ctx.Err() == nil {
+				p.fail(err)
+			}
+			return
+		}
+		// Pooled databases are always dropped on release, regardless of
+		// the teardown defaults for random database IDs, and free their slot
+		// however they are closed.
+		clients.dropDatabase = true
+		clients.afterClose = func() { p.live.Release(1) }
+		select {
+		case p.ready <- clients:
+		case <-p.ctx.Done():
+			logCloseError("release pooled database", clients.Close())
+			return
+		}
+	}
+}
+
+func (p *DatabasePool) fail(err error) {
+	p.failOnce.Do(func() {
+		p.failErr = fmt.Errorf("spanemuboost: database pool failed to prepare a database: %w", err)
+		close(p.failed)
+	})
+}
+
+// AcquireContext returns a prepared database, waiting for one if none is
+// ready. Call [Clients.Close] on the result to drop the database. Once
+// preparing a database has failed, AcquireContext returns that error.
+func (p *DatabasePool) AcquireContext(ctx context.Context) (*Clients, error) {
+	select {
+	case clients := <-p.ready:
+		return clients, nil
+	default:
+	}
+	select {
+	case clients := <-p.ready:
+		return clients, nil
+	case <-p.failed:
+		return nil, p.failErr
+	case <-p.ctx.Done():
+		return nil, errors.New("spanemuboost: database pool is closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Acquire returns a prepared database and registers its release via
+// [testing.TB.Cleanup]. The database is dropped in the background after the
+// test, so cleanup does not wait for it. It calls [testing.TB.Fatal] on error.
+func (p *DatabasePool) Acquire(tb testing.TB) *Clients {
+	tb.Helper()
+	clients, err := p.AcquireContext(tb.Context())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { p.release(clients) })
+	return clients
+}
+
+// release drops clients in the background, or synchronously if the pool is
+// already closed.
+func (p *DatabasePool) release(clients *Clients) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		logCloseError("release pooled database", clients.Close())
+		return
+	}
+	p.releases.Add(1)
+	go func() {
+		defer p.releases.Done()
+		logCloseError("release pooled database", clients.Close())
+	}()
+}
+
+// Close stops preparing databases, drops the prepared databases that were
+// not acquired, and waits for released databases to be dropped. Databases
+// acquired with [DatabasePool.AcquireContext] and not yet closed are left to
+// their callers. Close is nil-safe and idempotent.
+func (p *DatabasePool) Close() error {
+	if p == nil {
+		return nil
+	}
+	return p.closeState.close(func() error {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		p.cancel()
+		p.fillers.Wait()
+		p.releases.Wait()
+		return nil
+	})
+}
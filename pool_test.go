@@ -0,0 +1,110 @@
+package spanemuboost
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewDatabasePoolValidation(t *testing.T) {
+	opts, err := applyOptions()
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	emu := &Emulator{opts: opts}
+
+	tests := []struct {
+		name    string
+		size    int
+		options []Option
+		want    string
+	}{
+		{name: "zero size", size: 0, want: "NewDatabasePool: size must be > 0, got 0"},
+		{name: "over default limit", size: 101, want: "NewDatabasePool: size 101 exceeds the limit of 100 databases per instance"},
+		{name: "fixed database ID", size: 1, options: []Option{WithDatabaseID("fixed")}, want: "mutually exclusive"},
+		{name: "random database ID disabled", size: 1, options: []Option{WithoutRandomDatabaseID(), WithDatabaseID("fixed")}, want: `NewDatabasePool: options must not set a fixed database ID, got "fixed"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewDatabasePool(t.Context(), emu, tt.size, tt.options...)
+			if err == nil {
+				pool.Close()
+				t.Fatal("NewDatabasePool() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("NewDatabasePool() error = %v, want substring %q", err, tt.want)
+			}
+		})
+	}
+
+	var nilPool *DatabasePool
+	if err := nilPool.Close(); err != nil {
+		t.Fatalf("nil DatabasePool.Close() error = %v", err)
+	}
+}
+
+func TestMaxDatabasesPerInstanceIsInherited(t *testing.T) {
+	opts, err := applyOptions(WithMaxDatabasesPerInstance(500))
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	inherited, err := (&Emulator{opts: opts}).inheritedOptions()
+	if err != nil {
+		t.Fatalf("inheritedOptions: %v", err)
+	}
+	if inherited.maxDatabasesPerInstance != 500 {
+		t.Fatalf("maxDatabasesPerInstance = %d, want 500", inherited.maxDatabasesPerInstance)
+	}
+}
+
+func TestDatabasePool(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	pool := SetupDatabasePool(t, emu, 2,
+		WithSetupDDLs([]string{"CREATE TABLE tbl (pk INT64) PRIMARY KEY (pk)"}),
+		WithSetupRawDMLs([]string{"INSERT INTO tbl (pk) VALUES (1)"}),
+	)
+
+	count := func(t *testing.T, clients *Clients) int64 {
+		t.Helper()
+		var n int64
+		err := clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT COUNT(*) FROM tbl")).Do(func(r *spanner.Row) error {
+			return r.Column(0, &n)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	a := pool.Acquire(t)
+	b := pool.Acquire(t)
+	if a.DatabaseID == b.DatabaseID {
+		t.Fatalf("Acquire() returned database %q twice", a.DatabaseID)
+	}
+	if _, err := a.Client.Apply(t.Context(), []*spanner.Mutation{spanner.InsertMap("tbl", map[string]any{"pk": 2})}); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, a); got != 2 {
+		t.Fatalf("COUNT(*) in a = %d, want 2", got)
+	}
+	if got := count(t, b); got != 1 {
+		t.Fatalf("COUNT(*) in b = %d, want 1", got)
+	}
+
+	c, err := pool.AcquireContext(t.Context())
+	if err != nil {
+		t.Fatalf("AcquireContext() error = %v", err)
+	}
+	path := c.DatabasePath()
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	_, err = a.DatabaseClient.GetDatabase(t.Context(), &databasepb.GetDatabaseRequest{Name: path})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("GetDatabase() after Close error = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,246 @@
+package spanemuboost
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// WithSetupProtoMessages sets the proto descriptors for CREATE/ALTER PROTO
+// BUNDLE statements from Go message types, instead of a hand-assembled
+// [descriptorpb.FileDescriptorSet]. The files declaring msgs and all their
+// transitive imports are collected, each dependency before the files that
+// import it. Use [CreateProtoBundleStatement] for the matching DDL.
+//
+// Calling this and [WithSetupProtoEnums] multiple times adds to the collected
+// files. [WithSetupFileDescriptorSet] and [WithSetupRawFileDescriptorSet]
+// replace them.
+func WithSetupProtoMessages(msgs ...proto.Message) Option {
+	descs := make([]protoreflect.Descriptor, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg != nil {
+			descs = append(descs, msg.ProtoReflect().Descriptor())
+		}
+	}
+	return withSetupProtoDescriptors("WithSetupProtoMessages", descs)
+}
+
+// WithSetupProtoEnums is like [WithSetupProtoMessages] for Go enum types.
+func WithSetupProtoEnums(enums ...protoreflect.Enum) Option {
+	descs := make([]protoreflect.Descriptor, 0, len(enums))
+	for _, enum := range enums {
+		if enum != nil {
+			descs = append(descs, enum.Descriptor())
+		}
+	}
+	return withSetupProtoDescriptors("WithSetupProtoEnums", descs)
+}
+
+func withSetupProtoDescriptors(name string, descs []protoreflect.Descriptor) Option {
+	return func(opts *emulatorOptions) error {
+		if len(descs) == 0 {
+			return fmt.Errorf("%s: at least one type is required", name)
+		}
+		files := slices.Clone(opts.setupProtoTypeFiles)
+		for _, desc := range descs {
+			files = append(files, desc.ParentFile())
+		}
+		raw, err := proto.Marshal(fileDescriptorSetOf(files))
+		if err != nil {
+			return fmt.Errorf("%s: marshal file descriptor set: %w", name, err)
+		}
+		opts.setupProtoTypeFiles = files
+		opts.setupFileDescriptorSet = raw
+		return nil
+	}
+}
+
+// fileDescriptorSetOf returns the transitive closure of files in topological
+// order: every file comes after the files it imports. Files are visited in the
+// given order, so the result is deterministic.
+func fileDescriptorSetOf(files []protoreflect.FileDescriptor) *descriptorpb.FileDescriptorSet {
+	fds := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var visit func(fd protoreflect.FileDescriptor)
+	visit = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := range imports.Len() {
+			visit(imports.Get(i).FileDescriptor)
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range files {
+		visit(fd)
+	}
+	return fds
+}
+
+// CreateProtoBundleStatement returns a GoogleSQL CREATE PROTO BUNDLE statement
+// listing the fully-qualified names of descs, such as the descriptors of the
+// types given to [WithSetupProtoMessages] and [WithSetupProtoEnums]. Duplicate
+// types are listed once.
+//
+//	spanemuboost.CreateProtoBundleStatement(
+//	    (&pb.Order{}).ProtoReflect().Descriptor(),
+//	    pb.Order_Status(0).Descriptor(),
+//	)
+func CreateProtoBundleStatement(descs ...protoreflect.Descriptor) string {
+	var names []string
+	for _, desc := range descs {
+		name := "`" + string(desc.FullName()) + "`"
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return "CREATE PROTO BUNDLE (" + strings.Join(names, ", ") + ")"
+}
+
+// WithSetupFileDescriptorSetFile sets the proto descriptors for CREATE/ALTER
+// PROTO BUNDLE statements from a binary descriptor set file, such as the
+// output of `buf build -o descriptor.pb` or
+// `protoc --include_imports --descriptor_set_out=descriptor.pb`. The file is
+// validated when the option is applied: it must parse as a
+// [descriptorpb.FileDescriptorSet] and include every imported file.
+//
+// Calling this multiple times replaces the previous value, as
+// [WithSetupFileDescriptorSet] does. Use [WithSetupFileDescriptorSetFilter] to
+// send only the files that the setup DDLs need.
+func WithSetupFileDescriptorSetFile(path string) Option {
+	return func(opts *emulatorOptions) error {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("WithSetupFileDescriptorSetFile: %w", err)
+		}
+		if _, err := parseFileDescriptorSet(raw); err != nil {
+			return fmt.Errorf("WithSetupFileDescriptorSetFile: %s: %w", path, err)
+		}
+		opts.setupFileDescriptorSet = raw
+		opts.setupProtoTypeFiles = nil
+		return nil
+	}
+}
+
+// WithSetupFileDescriptorSetFS is like [WithSetupFileDescriptorSetFile] for a
+// file in fsys, such as an embed.FS.
+func WithSetupFileDescriptorSetFS(fsys fs.FS, name string) Option {
+	return func(opts *emulatorOptions) error {
+		if fsys == nil {
+			return fmt.Errorf("WithSetupFileDescriptorSetFS: fs.FS is nil")
+		}
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("WithSetupFileDescriptorSetFS: %w", err)
+		}
+		if _, err := parseFileDescriptorSet(raw); err != nil {
+			return fmt.Errorf("WithSetupFileDescriptorSetFS: %s: %w", name, err)
+		}
+		opts.setupFileDescriptorSet = raw
+		opts.setupProtoTypeFiles = nil
+		return nil
+	}
+}
+
+// WithSetupFileDescriptorSetFilter trims the setup proto descriptors to the
+// files declaring the types inserted or updated by the CREATE/ALTER PROTO
+// BUNDLE statements of the setup DDLs and migrations, and the files they
+// import, so that a large descriptor set does not bloat the DDL requests.
+// Filtering is per file: other types declared in a needed file are kept.
+//
+// A bundle type missing from the descriptor set is reported as an error. If
+// the setup DDLs and migrations have no proto bundle statements, for example
+// because they are run by a [DDLStep], the descriptor set is left unchanged.
+func WithSetupFileDescriptorSetFilter() Option {
+	return func(opts *emulatorOptions) error {
+		opts.filterFileDescriptorSet = true
+		return nil
+	}
+}
+
+func parseFileDescriptorSet(raw []byte) (*protoregistry.Files, error) {
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(raw, &fds); err != nil {
+		return nil, fmt.Errorf("unmarshal file descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file descriptor set (protoc needs --include_imports): %w", err)
+	}
+	return files, nil
+}
+
+// filterSetupFileDescriptorSet applies [WithSetupFileDescriptorSetFilter].
+// It is idempotent so that options can be finalized again when inherited.
+func filterSetupFileDescriptorSet(opts *emulatorOptions) error {
+	if !opts.filterFileDescriptorSet || len(opts.setupFileDescriptorSet) == 0 {
+		return nil
+	}
+	ddls := slices.Clone(opts.setupDDLs)
+	for _, m := range opts.migrations {
+		ddls = append(ddls, m.ddls...)
+	}
+	names := protoBundleTypeNames(ddls)
+	if len(names) == 0 {
+		return nil
+	}
+
+	files, err := parseFileDescriptorSet(opts.setupFileDescriptorSet)
+	if err != nil {
+		return fmt.Errorf("WithSetupFileDescriptorSetFilter: %w", err)
+	}
+	var needed []protoreflect.FileDescriptor
+	for _, name := range names {
+		desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return fmt.Errorf("WithSetupFileDescriptorSetFilter: proto bundle type %q is not in the setup file descriptor set", name)
+		}
+		needed = append(needed, desc.ParentFile())
+	}
+	raw, err := proto.Marshal(fileDescriptorSetOf(needed))
+	if err != nil {
+		return fmt.Errorf("WithSetupFileDescriptorSetFilter: marshal file descriptor set: %w", err)
+	}
+	opts.setupFileDescriptorSet = raw
+	return nil
+}
+
+var (
+	protoBundleStatement = regexp.MustCompile(`(?is)^\s*(?:CREATE|ALTER)\s+PROTO\s+BUNDLE\b(.*)$`)
+	protoBundleClause    = regexp.MustCompile(`(?is)(?:\b(INSERT|UPDATE|DELETE)\s*)?\(([^)]*)\)`)
+)
+
+// protoBundleTypeNames returns the types that CREATE/ALTER PROTO BUNDLE
+// statements in ddls create, insert, or update, in order of appearance.
+func protoBundleTypeNames(ddls []string) []string {
+	var names []string
+	for _, ddl := range ddls {
+		m := protoBundleStatement.FindStringSubmatch(ddl)
+		if m == nil {
+			continue
+		}
+		for _, clause := range protoBundleClause.FindAllStringSubmatch(m[1], -1) {
+			if strings.EqualFold(clause[1], "DELETE") {
+				continue
+			}
+			for _, name := range strings.Split(clause[2], ",") {
+				name = strings.Trim(strings.TrimSpace(name), "`")
+				if name != "" && !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
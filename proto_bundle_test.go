@@ -1,10 +1,19 @@
 package spanemuboost
 
 import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -118,3 +127,198 @@ func exampleShippingFileDescriptorSet() *descriptorpb.FileDescriptorSet {
 		},
 	}
 }
+
+func TestWithSetupProtoMessagesCollectsDependenciesFirst(t *testing.T) {
+	opts := &emulatorOptions{}
+	if err := WithSetupProtoMessages(&sppb.StructType{})(opts); err != nil {
+		t.Fatalf("WithSetupProtoMessages() error = %v", err)
+	}
+	if err := WithSetupProtoEnums(sppb.TypeAnnotationCode(0))(opts); err != nil {
+		t.Fatalf("WithSetupProtoEnums() error = %v", err)
+	}
+
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(opts.setupFileDescriptorSet, &fds); err != nil {
+		t.Fatal(err)
+	}
+	index := make(map[string]int)
+	for i, file := range fds.GetFile() {
+		if _, ok := index[file.GetName()]; ok {
+			t.Fatalf("file %s is collected twice", file.GetName())
+		}
+		index[file.GetName()] = i
+	}
+	for _, file := range fds.GetFile() {
+		for _, dep := range file.GetDependency() {
+			i, ok := index[dep]
+			if !ok {
+				t.Fatalf("dependency %s of %s is not collected", dep, file.GetName())
+			}
+			if i > index[file.GetName()] {
+				t.Fatalf("dependency %s comes after %s", dep, file.GetName())
+			}
+		}
+	}
+	if _, ok := index["google/spanner/v1/type.proto"]; !ok {
+		t.Fatalf("files = %v, want google/spanner/v1/type.proto", slices.Collect(maps.Keys(index)))
+	}
+
+	if err := WithSetupRawFileDescriptorSet(nil)(opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.setupProtoTypeFiles) != 0 || len(opts.setupFileDescriptorSet) != 0 {
+		t.Fatal("WithSetupRawFileDescriptorSet did not replace the collected proto types")
+	}
+}
+
+func TestWithSetupProtoMessagesRequiresTypes(t *testing.T) {
+	if err := WithSetupProtoMessages()(&emulatorOptions{}); err == nil {
+		t.Fatal("WithSetupProtoMessages() error = nil, want error")
+	}
+}
+
+func TestCreateProtoBundleStatement(t *testing.T) {
+	got := CreateProtoBundleStatement(
+		(&sppb.StructType{}).ProtoReflect().Descriptor(),
+		sppb.TypeCode(0).Descriptor(),
+		(&sppb.StructType{}).ProtoReflect().Descriptor(),
+	)
+	want := "CREATE PROTO BUNDLE (`google.spanner.v1.StructType`, `google.spanner.v1.TypeCode`)"
+	if got != want {
+		t.Fatalf("CreateProtoBundleStatement() = %q, want %q", got, want)
+	}
+}
+
+func TestSetupClientsProtoBundleFromGoTypes(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	clients := SetupClients(t, emu,
+		WithRandomDatabaseID(),
+		WithSetupProtoMessages(&sppb.StructType{}),
+		WithSetupProtoEnums(sppb.TypeCode(0)),
+		WithSetupDDLs([]string{
+			CreateProtoBundleStatement(
+				(&sppb.StructType{}).ProtoReflect().Descriptor(),
+				sppb.TypeCode(0).Descriptor(),
+			),
+			"CREATE TABLE t (pk INT64, s `google.spanner.v1.StructType`, c `google.spanner.v1.TypeCode`) PRIMARY KEY (pk)",
+		}),
+	)
+
+	schema, err := clients.Schema(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.ProtoBundle.Types; !slices.Contains(got, "google.spanner.v1.StructType") {
+		t.Fatalf("proto bundle types = %v, want google.spanner.v1.StructType", got)
+	}
+}
+
+func writeFileDescriptorSet(t *testing.T, fds *descriptorpb.FileDescriptorSet) string {
+	t.Helper()
+	raw, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "descriptor.pb")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWithSetupFileDescriptorSetFile(t *testing.T) {
+	path := writeFileDescriptorSet(t, exampleShippingFileDescriptorSet())
+	opts := &emulatorOptions{}
+	if err := WithSetupFileDescriptorSetFile(path)(opts); err != nil {
+		t.Fatalf("WithSetupFileDescriptorSetFile() error = %v", err)
+	}
+	if len(opts.setupFileDescriptorSet) == 0 {
+		t.Fatal("WithSetupFileDescriptorSetFile() did not set the descriptor set")
+	}
+
+	// protoc without --include_imports leaves out imported files.
+	fds := fileDescriptorSetOf([]protoreflect.FileDescriptor{(&sppb.StructType{}).ProtoReflect().Descriptor().ParentFile()})
+	fds.File = fds.File[len(fds.File)-1:]
+	err := WithSetupFileDescriptorSetFile(writeFileDescriptorSet(t, fds))(&emulatorOptions{})
+	if err == nil || !strings.Contains(err.Error(), "--include_imports") {
+		t.Fatalf("WithSetupFileDescriptorSetFile() error = %v, want a hint about --include_imports", err)
+	}
+
+	if err := WithSetupFileDescriptorSetFS(fstest.MapFS{"descriptor.pb": {Data: []byte("not a descriptor set")}}, "descriptor.pb")(&emulatorOptions{}); err == nil {
+		t.Fatal("WithSetupFileDescriptorSetFS() error = nil for an invalid file, want error")
+	}
+}
+
+func TestWithSetupFileDescriptorSetFilter(t *testing.T) {
+	fds := fileDescriptorSetOf([]protoreflect.FileDescriptor{(&sppb.StructType{}).ProtoReflect().Descriptor().ParentFile()})
+	fds.File = append(fds.File, exampleShippingFileDescriptorSet().GetFile()...)
+	raw, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := applyOptions(
+		WithSetupRawFileDescriptorSet(raw),
+		WithSetupFileDescriptorSetFilter(),
+		WithSetupDDLs([]string{"CREATE PROTO BUNDLE (`examples.shipping.Order`)"}),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions() error = %v", err)
+	}
+	var got descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(opts.setupFileDescriptorSet, &got); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range got.GetFile() {
+		names = append(names, file.GetName())
+	}
+	if diff := cmp.Diff([]string{"shipping.proto"}, names); diff != "" {
+		t.Fatalf("filtered files mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = applyOptions(
+		WithSetupRawFileDescriptorSet(raw),
+		WithSetupFileDescriptorSetFilter(),
+		WithSetupDDLs([]string{"CREATE PROTO BUNDLE (`examples.shipping.Missing`)"}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "examples.shipping.Missing") {
+		t.Fatalf("applyOptions() error = %v, want the missing type", err)
+	}
+}
+
+func TestWithSetupFileDescriptorSetFilterOmni(t *testing.T) {
+	fds := fileDescriptorSetOf([]protoreflect.FileDescriptor{(&sppb.StructType{}).ProtoReflect().Descriptor().ParentFile()})
+	fds.File = append(fds.File, exampleShippingFileDescriptorSet().GetFile()...)
+	raw, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := applyOmniOptions(
+		WithSetupRawFileDescriptorSet(raw),
+		WithSetupFileDescriptorSetFilter(),
+		WithSetupDDLs([]string{"CREATE PROTO BUNDLE (`examples.shipping.Order`)"}),
+	)
+	if err != nil {
+		t.Fatalf("applyOmniOptions() error = %v", err)
+	}
+	var got descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(opts.setupFileDescriptorSet, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.GetFile()) != 1 || got.GetFile()[0].GetName() != "shipping.proto" {
+		t.Fatalf("filtered files = %d, want only shipping.proto", len(got.GetFile()))
+	}
+}
+
+func TestProtoBundleTypeNames(t *testing.T) {
+	got := protoBundleTypeNames([]string{
+		"CREATE TABLE t (pk INT64) PRIMARY KEY (pk)",
+		"CREATE PROTO BUNDLE (`a.B`, a.C)",
+		"ALTER PROTO BUNDLE INSERT (`a.D`) UPDATE (`a.B`) DELETE (`a.E`)",
+	})
+	if diff := cmp.Diff([]string{"a.B", "a.C", "a.D"}, got); diff != "" {
+		t.Fatalf("protoBundleTypeNames() mismatch (-want +got):\n%s", diff)
+	}
+}
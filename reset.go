@@ -0,0 +1,121 @@
+package spanemuboost
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+)
+
+// ResetOption configures [Clients.ResetData].
+type ResetOption func(*resetOptions)
+
+type resetOptions struct {
+	keepTables      []string
+	reapplyFixtures bool
+}
+
+// ResetKeepTables excludes tables from [Clients.ResetData], for example a
+// migration history table. Names may be schema-qualified and are resolved like
+// fixture table names.
+func ResetKeepTables(tables ...string) ResetOption {
+	return func(opts *resetOptions) {
+		opts.keepTables = append(opts.keepTables, tables...)
+	}
+}
+
+// ResetReapplyFixtures makes [Clients.ResetData] insert the fixtures of
+// [WithSetupFixtures] again after deleting the rows.
+func ResetReapplyFixtures() ResetOption {
+	return func(opts *resetOptions) {
+		opts.reapplyFixtures = true
+	}
+}
+
+// ResetData deletes all rows of every user table in the database, keeping the
+// schema, so that serial tests can share one database.
+//
+// Tables are discovered through INFORMATION_SCHEMA in both dialects, and all
+// rows are deleted in one read-write transaction, interleaved children and
+// referencing tables before their parents and referenced tables. Tables
+// watched by change streams are reset with regular deletes, so the change
+// streams record the deletions.
+//
+// Setup mutations, DMLs, and steps are not applied again. Use
+// [ResetReapplyFixtures] to insert the setup fixtures again.
+func (c *Clients) ResetData(ctx context.Context, options ...ResetOption) error {
+	if err := c.requireInformationSchema("Clients.ResetData"); err != nil {
+		return err
+	}
+	var opts resetOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	setup := c.schemaOrDefault()
+	schema, err := loadFixtureSchema(ctx, c.Client, setup.databaseDialect)
+	if err != nil {
+		return fmt.Errorf("failed to read schema for reset: %w", err)
+	}
+
+	keep := make(map[*fixtureTable]bool)
+	for _, name := range opts.keepTables {
+		t, err := schema.lookupTable(name)
+		if err != nil {
+			return fmt.Errorf("ResetKeepTables: %w", err)
+		}
+		keep[t] = true
+	}
+
+	var mutations []*spanner.Mutation
+	for _, t := range deletionOrder(schema) {
+		if !keep[t] {
+			mutations = append(mutations, spanner.Delete(t.qualifiedName(), spanner.AllKeys()))
+		}
+	}
+	if len(mutations) > 0 {
+		if _, err := c.Client.Apply(ctx, mutations); err != nil {
+			return fmt.Errorf("failed to delete rows: %w", err)
+		}
+	}
+
+	if opts.reapplyFixtures && len(setup.setupFixtures) > 0 {
+		return applyFixtures(ctx, setup, c.Client)
+	}
+	return nil
+}
+
+// deletionOrder orders the tables of schema so that each table comes before
+// its parent and the tables it references. A dependency cycle is broken at
+// the table reached first.
+func deletionOrder(schema *fixtureSchema) []*fixtureTable {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var order []*fixtureTable
+	var visit func(t *fixtureTable)
+	visit = func(t *fixtureTable) {
+		name := t.qualifiedName()
+		if state[name] != unvisited {
+			return
+		}
+		state[name] = visiting
+		for _, dep := range t.deps {
+			if d, ok := schema.tables[dep]; ok && dep != name {
+				visit(d)
+			}
+		}
+		state[name] = done
+		order = append(order, t)
+	}
+	for _, name := range slices.Sorted(maps.Keys(schema.tables)) {
+		visit(schema.tables[name])
+	}
+	slices.Reverse(order)
+	return order
+}
@@ -0,0 +1,82 @@
+package spanemuboost
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeletionOrder(t *testing.T) {
+	schema := &fixtureSchema{tables: map[string]*fixtureTable{
+		"Albums":  {name: "Albums", deps: []string{"Singers"}},
+		"Labels":  {name: "Labels"},
+		"Singers": {name: "Singers", deps: []string{"Labels"}},
+		"Songs":   {name: "Songs", deps: []string{"Albums", "Songs"}},
+		"Tours":   {name: "Tours", deps: []string{"Singers", "missing"}},
+	}}
+	var got []string
+	for _, table := range deletionOrder(schema) {
+		got = append(got, table.qualifiedName())
+	}
+	pos := make(map[string]int)
+	for i, name := range got {
+		pos[name] = i
+	}
+	for _, edge := range [][2]string{{"Albums", "Singers"}, {"Singers", "Labels"}, {"Songs", "Albums"}, {"Tours", "Singers"}} {
+		if pos[edge[0]] > pos[edge[1]] {
+			t.Errorf("deletionOrder() = %v, want %s before %s", got, edge[0], edge[1])
+		}
+	}
+	if len(got) != len(schema.tables) {
+		t.Fatalf("deletionOrder() = %v, want every table once", got)
+	}
+}
+
+func TestClientsResetData(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/Singers.csv": {Data: []byte("SingerId,Name\n1,Alice\n")},
+	}
+	clients := SetupEmulatorWithClients(t,
+		WithSetupDDLs([]string{
+			"CREATE TABLE Singers (SingerId INT64, Name STRING(MAX)) PRIMARY KEY (SingerId)",
+			"CREATE TABLE Albums (SingerId INT64, AlbumId INT64) PRIMARY KEY (SingerId, AlbumId), INTERLEAVE IN PARENT Singers",
+			`CREATE TABLE Concerts (ConcertId INT64, SingerId INT64,
+  CONSTRAINT FK_Singer FOREIGN KEY (SingerId) REFERENCES Singers (SingerId)) PRIMARY KEY (ConcertId)`,
+			"CREATE TABLE History (Version INT64) PRIMARY KEY (Version)",
+			"CREATE CHANGE STREAM SingersStream FOR Singers",
+		}),
+		WithSetupFixtures(fsys, "fixtures/*"),
+	).Clients
+
+	_, err := clients.Client.Apply(t.Context(), []*spanner.Mutation{
+		spanner.InsertMap("Singers", map[string]any{"SingerId": 2, "Name": "Bob"}),
+		spanner.InsertMap("Albums", map[string]any{"SingerId": 1, "AlbumId": 10}),
+		spanner.InsertMap("Concerts", map[string]any{"ConcertId": 100, "SingerId": 2}),
+		spanner.InsertMap("History", map[string]any{"Version": 1}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := clients.ResetData(t.Context(), ResetKeepTables("History"), ResetReapplyFixtures()); err != nil {
+		t.Fatalf("ResetData() error = %v", err)
+	}
+
+	var got []int64
+	for _, table := range []string{"Singers", "Albums", "Concerts", "History"} {
+		var n int64
+		err := clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT COUNT(*) FROM "+table)).Do(func(r *spanner.Row) error {
+			return r.Column(0, &n)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n)
+	}
+	// Singers holds the reapplied fixture row, and History is kept.
+	if diff := cmp.Diff([]int64{1, 0, 0, 1}, got); diff != "" {
+		t.Fatalf("row counts mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -11,7 +11,8 @@ import (
 )
 
 // Backend identifies the runtime implementation to start.
-// Callers should use the exported Backend* constants; other values are rejected.
+// Callers should use the exported Backend* constants or a backend registered
+// with [RegisterBackend]; other values are rejected.
 type Backend string
 
 const (
@@ -29,6 +30,27 @@ const (
 	//
 	// Use [RecommendedOmniClientConfig] for external Go clients.
 	BackendOmni Backend = "omni"
+	// BackendInProcess serves the Spanner gRPC API on a loopback listener from
+	// the in-memory fake in cloud.google.com/go/spanner/spannertest, so it
+	// needs no container runtime. Each database is backed by its own fake.
+	//
+	// The fake implements a subset of GoogleSQL: the PostgreSQL dialect, proto
+	// bundles, change streams, INFORMATION_SCHEMA, batch DML, foreign keys,
+	// DEFAULT values, and many functions and query features are missing.
+	// Backend guardrails reject the PostgreSQL dialect, proto bundles, change
+	// streams, emulator gateway flags, and [WithSetupFixtures]. Helpers that
+	// read INFORMATION_SCHEMA, such as [Clients.Schema], [Clients.Snapshot],
+	// [Clients.Restore], [Clients.ResetData], [DumpDatabase], [DumpRuntime],
+	// [WithCopyRows], and [WithForkData], return an error. The other missing
+	// features fail when used. Use [BackendEmulator] for them.
+	BackendInProcess Backend = "inprocess"
+	// BackendEmulatorBinary runs the Cloud Spanner Emulator release binaries,
+	// gateway_main and emulator_main, as a child process group on free loopback
+	// ports instead of in a container, for hosts without a container runtime.
+	// The binaries are published for Linux; locate them with
+	// [WithEmulatorBinary]. Container options are ignored, while emulator
+	// gateway flag options such as [WithMaxDatabasesPerInstance] apply.
+	BackendEmulatorBinary Backend = "emulator-binary"
 )
 
 // RuntimeHandle is a package-provided runtime value accepted by [OpenClients]
@@ -48,7 +70,8 @@ type RuntimeHandle interface {
 // entry point. Backend-specific behavior may evolve independently, especially
 // for the experimental [BackendOmni] backend.
 //
-// Implementations are provided by this package.
+// Implementations are provided by this package. To add a backend, implement
+// [BackendProvider] instead.
 type Runtime interface {
 	RuntimeHandle
 	URI() string
@@ -87,13 +110,15 @@ func RuntimePlatform(ctx context.Context, runtime RuntimeHandle) (string, error)
 
 func inheritedRuntimeOptions(opts *emulatorOptions) *emulatorOptions {
 	base := &emulatorOptions{
-		projectID:             opts.projectID,
-		instanceID:            opts.instanceID,
-		databaseID:            opts.databaseID,
-		databaseDialect:       opts.databaseDialect,
-		disableCreateInstance: true,
-		disableCreateDatabase: true,
-		reuseExistingDatabase: true,
+		projectID:               opts.projectID,
+		instanceID:              opts.instanceID,
+		databaseID:              opts.databaseID,
+		databaseDialect:         opts.databaseDialect,
+		disableCreateInstance:   true,
+		disableCreateDatabase:   true,
+		reuseExistingDatabase:   true,
+		schemaTemplates:         opts.schemaTemplates,
+		maxDatabasesPerInstance: opts.maxDatabasesPerInstance,
 	}
 	if opts.clientConfig != nil {
 		config := *opts.clientConfig
@@ -148,6 +173,12 @@ func resolveRuntime(ctx context.Context, runtime RuntimeHandle) (runtimeInstance
 			return instance, nil
 		case *omniRuntime:
 			return instance, nil
+		case *inProcessRuntime:
+			return instance, nil
+		case *emulatorBinaryRuntime:
+			return instance, nil
+		case *providedRuntime:
+			return instance, nil
 		case *AttachedRuntime:
 			return instance, nil
 		default:
@@ -213,7 +244,14 @@ func Run(ctx context.Context, backend Backend, options ...Option) (Runtime, erro
 		return RunEmulator(ctx, options...)
 	case BackendOmni:
 		return runOmni(ctx, options...)
+	case BackendInProcess:
+		return runInProcess(ctx, options...)
+	case BackendEmulatorBinary:
+		return runEmulatorBinary(ctx, options...)
 	default:
+		if provider, ok := lookupBackendProvider(backend); ok {
+			return runProvided(ctx, backend, provider, options...)
+		}
 		return nil, fmt.Errorf("unsupported backend %q", backend)
 	}
 }
@@ -232,7 +270,14 @@ func RunWithClients(ctx context.Context, backend Backend, options ...Option) (*R
 		return &RuntimeEnv{Clients: env.Clients, runtime: env.Emulator()}, nil
 	case BackendOmni:
 		return runOmniWithClients(ctx, options...)
+	case BackendInProcess:
+		return runInProcessWithClients(ctx, options...)
+	case BackendEmulatorBinary:
+		return runEmulatorBinaryWithClients(ctx, options...)
 	default:
+		if provider, ok := lookupBackendProvider(backend); ok {
+			return runProvidedWithClients(ctx, backend, provider, options...)
+		}
 		return nil, fmt.Errorf("unsupported backend %q", backend)
 	}
 }
@@ -251,7 +296,14 @@ func Setup(tb testing.TB, backend Backend, options ...Option) Runtime {
 		return SetupEmulator(tb, options...)
 	case BackendOmni:
 		return setupOmni(tb, options...)
+	case BackendInProcess:
+		return setupInProcess(tb, options...)
+	case BackendEmulatorBinary:
+		return setupEmulatorBinary(tb, options...)
 	default:
+		if provider, ok := lookupBackendProvider(backend); ok {
+			return setupProvided(tb, backend, provider, options...)
+		}
 		tb.Fatalf("unsupported backend %q", backend)
 		return nil
 	}
@@ -272,7 +324,14 @@ func SetupWithClients(tb testing.TB, backend Backend, options ...Option) *Runtim
 		return &RuntimeEnv{Clients: env.Clients, runtime: env.Emulator()}
 	case BackendOmni:
 		return setupOmniWithClients(tb, options...)
+	case BackendInProcess:
+		return setupInProcessWithClients(tb, options...)
+	case BackendEmulatorBinary:
+		return setupEmulatorBinaryWithClients(tb, options...)
 	default:
+		if provider, ok := lookupBackendProvider(backend); ok {
+			return setupProvidedWithClients(tb, backend, provider, options...)
+		}
 		tb.Fatalf("unsupported backend %q", backend)
 		return nil
 	}
@@ -0,0 +1,258 @@
+package spanemuboost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/testcontainers/testcontainers-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const defaultPolicyProbeTimeout = 5 * time.Second
+
+// RuntimeCandidate is one way to obtain a runtime in a [RuntimePolicy]: either
+// attaching to an external endpoint of Backend, or starting Backend.
+type RuntimeCandidate struct {
+	Backend Backend
+	Attach  bool
+}
+
+// AttachCandidate attaches to the endpoint configured for backend by
+// SPANEMUBOOST_ENDPOINT_FILE or the backend's URI env var, as
+// [NewLazyRuntimeFromEnvOrStart] does. It is skipped when no endpoint is
+// configured or the endpoint does not answer a health probe.
+func AttachCandidate(backend Backend) RuntimeCandidate {
+	return RuntimeCandidate{Backend: backend, Attach: true}
+}
+
+// StartCandidate starts backend. It is skipped when backend cannot run on this
+// host: [BackendEmulator] and [BackendOmni] need a reachable container
+// runtime, and [BackendEmulatorBinary] needs the emulator binaries.
+// [BackendInProcess] and registered backends are never skipped.
+func StartCandidate(backend Backend) RuntimeCandidate {
+	return RuntimeCandidate{Backend: backend}
+}
+
+func (c RuntimeCandidate) String() string {
+	if c.Attach {
+		return "attach " + string(c.Backend)
+	}
+	return "start " + string(c.Backend)
+}
+
+// RuntimePolicy lists the candidates that [NewLazyRuntimeWithPolicy] tries in
+// order.
+type RuntimePolicy struct {
+	Candidates []RuntimeCandidate
+
+	// ProbeTimeout bounds each availability check, such as the health probe
+	// of an attached endpoint. Zero means 5 seconds.
+	ProbeTimeout time.Duration
+
+	// Logf receives the decision once it is made. Nil means [log.Printf].
+	Logf func(format string, args ...any)
+}
+
+// DefaultRuntimePolicy attaches to a configured emulator endpoint, then starts
+// an emulator container, and then falls back to [BackendInProcess], which
+// rejects the options and helpers it cannot support.
+func DefaultRuntimePolicy() RuntimePolicy {
+	return RuntimePolicy{Candidates: []RuntimeCandidate{
+		AttachCandidate(BackendEmulator),
+		StartCandidate(BackendEmulator),
+		StartCandidate(BackendInProcess),
+	}}
+}
+
+// RuntimeDecision records which candidate of a [RuntimePolicy] was used and
+// why the earlier candidates were skipped.
+type RuntimeDecision struct {
+	Candidate RuntimeCandidate
+	Skipped   []SkippedCandidate
+}
+
+// SkippedCandidate is a candidate that was unavailable, with the reason.
+type SkippedCandidate struct {
+	Candidate RuntimeCandidate
+	Reason    string
+}
+
+func (d RuntimeDecision) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "spanemuboost: runtime policy chose %s", d.Candidate)
+	for i, skipped := range d.Skipped {
+		if i == 0 {
+			b.WriteString("; skipped ")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s (%s)", skipped.Candidate, skipped.Reason)
+	}
+	return b.String()
+}
+
+// NewLazyRuntimeWithPolicy creates a [LazyRuntime] that, on first use, tries
+// the candidates of policy in order and uses the first available one, for
+// example an already-running endpoint, then a container, then an in-process
+// fake. The decision is logged and available from [LazyRuntime.Decision].
+//
+// A candidate is skipped only when it is unavailable. Once a candidate is
+// chosen, its startup or setup errors are returned without trying the next
+// one, so that a broken schema is not masked by a fallback. If every
+// candidate is skipped, first use fails with the reasons.
+//
+// The same options are used for every candidate, and backend guardrails
+// apply to the chosen backend.
+func NewLazyRuntimeWithPolicy(policy RuntimePolicy, options ...Option) *LazyRuntime {
+	return &LazyRuntime{opts: options, policy: &policy}
+}
+
+// Decision returns the decision of a runtime created by
+// [NewLazyRuntimeWithPolicy]. It reports false until the first use has
+// chosen a candidate, and for runtimes created otherwise.
+func (lr *LazyRuntime) Decision() (RuntimeDecision, bool) {
+	if lr == nil {
+		return RuntimeDecision{}, false
+	}
+	decision := lr.decision.Load()
+	if decision == nil {
+		return RuntimeDecision{}, false
+	}
+	return *decision, true
+}
+
+func (lr *LazyRuntime) startWithPolicy(ctx context.Context) (runtimeInstance, error) {
+	policy := lr.policy
+	logf := policy.Logf
+	if logf == nil {
+		logf = log.Printf
+	}
+	timeout := policy.ProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultPolicyProbeTimeout
+	}
+
+	// The options are resolved once to locate the container runtime and the
+	// emulator binary; each backend applies them again when it starts.
+	opts, err := applyOptions(lr.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var skipped []SkippedCandidate
+	for _, candidate := range policy.Candidates {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		endpoint, err := checkRuntimeCandidate(probeCtx, candidate, opts)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			skipped = append(skipped, SkippedCandidate{Candidate: candidate, Reason: err.Error()})
+			continue
+		}
+
+		decision := &RuntimeDecision{Candidate: candidate, Skipped: skipped}
+		lr.decision.Store(decision)
+		logf("%s", decision)
+		if endpoint != nil {
+			return NewAttachedRuntime(*endpoint, lr.opts...)
+		}
+		runtime, err := Run(ctx, candidate.Backend, lr.opts...)
+		if err != nil {
+			return nil, err
+		}
+		instance, ok := runtime.(runtimeInstance)
+		if !ok {
+			return nil, fmt.Errorf("spanemuboost: lazy runtime backend %q returned unexpected runtime type %T", candidate.Backend, runtime)
+		}
+		return instance, nil
+	}
+
+	var reasons []string
+	for _, s := range skipped {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", s.Candidate, s.Reason))
+	}
+	if len(reasons) == 0 {
+		return nil, errors.New("spanemuboost: runtime policy has no candidates")
+	}
+	return nil, fmt.Errorf("spanemuboost: no runtime policy candidate is available: %s", strings.Join(reasons, "; "))
+}
+
+// checkRuntimeCandidate returns nil if candidate is available, along with the
+// endpoint to attach to for an attach candidate.
+func checkRuntimeCandidate(ctx context.Context, candidate RuntimeCandidate, opts *emulatorOptions) (*Endpoint, error) {
+	if candidate.Attach {
+		if !EndpointConfiguredForBackend(candidate.Backend) {
+			return nil, errors.New("no endpoint configured")
+		}
+		endpoint, err := LoadEndpointForBackend(candidate.Backend)
+		if err != nil {
+			return nil, err
+		}
+		if err := probeEndpoint(ctx, endpoint); err != nil {
+			return nil, fmt.Errorf("endpoint %s did not answer: %w", endpoint.URI, err)
+		}
+		return &endpoint, nil
+	}
+
+	switch candidate.Backend {
+	case BackendEmulator, BackendOmni:
+		if err := checkContainerRuntime(ctx, opts); err != nil {
+			return nil, fmt.Errorf("container runtime unavailable: %w", err)
+		}
+	case BackendEmulatorBinary:
+		if _, err := resolveEmulatorBinary(opts); err != nil {
+			return nil, err
+		}
+	case BackendInProcess:
+	default:
+		if _, ok := lookupBackendProvider(candidate.Backend); !ok {
+			return nil, fmt.Errorf("unsupported backend %q", candidate.Backend)
+		}
+	}
+	return nil, nil
+}
+
+func probeEndpoint(ctx context.Context, endpoint Endpoint) error {
+	conn, err := grpc.NewClient("passthrough:///"+endpoint.URI, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return probeInstanceAdmin(ctx, instancepb.NewInstanceAdminClient(conn), endpoint.ProjectID)
+}
+
+// probeInstanceAdmin returns nil if a Spanner API server answers through
+// client. Any reply other than UNAVAILABLE or DEADLINE_EXCEEDED, including
+// errors such as UNIMPLEMENTED, means that the server is up.
+func probeInstanceAdmin(ctx context.Context, client instancepb.InstanceAdminClient, projectID string) error {
+	_, err := client.ListInstanceConfigs(ctx, &instancepb.ListInstanceConfigsRequest{Parent: projectPath(projectID)})
+	if code := status.Code(err); code == codes.Unavailable || code == codes.DeadlineExceeded {
+		return err
+	}
+	return nil
+}
+
+// checkContainerRuntime pings the container runtime selected by the
+// container provider options or SPANEMUBOOST_TESTCONTAINERS_PROVIDER.
+func checkContainerRuntime(ctx context.Context, opts *emulatorOptions) error {
+	// The provider customizers only set the request's provider type.
+	var req testcontainers.GenericContainerRequest
+	for _, customizer := range opts.containerCustomizers {
+		_ = customizer.Customize(&req)
+	}
+	provider, err := req.ProviderType.GetProvider()
+	if err != nil {
+		return err
+	}
+	return provider.Health(ctx)
+}
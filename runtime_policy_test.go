@@ -0,0 +1,128 @@
+package spanemuboost
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// clearEndpointEnv makes the host's endpoint and emulator binary settings
+// invisible to the test.
+func clearEndpointEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{endpointFileEnv, omniURIEnv, emulatorURIEnv, emulatorBinaryEnv} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("PATH", t.TempDir())
+}
+
+type policyLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *policyLog) logf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestNewLazyRuntimeWithPolicyFallsBack(t *testing.T) {
+	clearEndpointEnv(t)
+	port, err := freeLoopbackPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A stale endpoint: nothing listens on the port any more.
+	t.Setenv(emulatorURIEnv, fmt.Sprintf("127.0.0.1:%d", port))
+
+	var logs policyLog
+	runtime := NewLazyRuntimeWithPolicy(RuntimePolicy{
+		Candidates: []RuntimeCandidate{
+			AttachCandidate(BackendEmulator),
+			StartCandidate(BackendEmulatorBinary),
+			StartCandidate(BackendInProcess),
+		},
+		ProbeTimeout: 200 * time.Millisecond,
+		Logf:         logs.logf,
+	}, EnableInstanceAutoConfigOnly())
+	t.Cleanup(func() {
+		if err := runtime.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	if _, ok := runtime.Decision(); ok {
+		t.Fatal("Decision() before first use reported a decision")
+	}
+
+	clients := SetupClients(t, runtime, WithRandomDatabaseID(), ForceSchemaTeardown(), WithSetupDDLs(inProcessTestDDLs))
+	if got := readSingerNames(t, clients.Client); len(got) != 0 {
+		t.Fatalf("rows = %v, want none", got)
+	}
+
+	decision, ok := runtime.Decision()
+	if !ok {
+		t.Fatal("Decision() after first use reported no decision")
+	}
+	if decision.Candidate != StartCandidate(BackendInProcess) {
+		t.Fatalf("Decision().Candidate = %v, want %v", decision.Candidate, StartCandidate(BackendInProcess))
+	}
+	var skipped []RuntimeCandidate
+	for _, s := range decision.Skipped {
+		skipped = append(skipped, s.Candidate)
+	}
+	if diff := cmp.Diff([]RuntimeCandidate{AttachCandidate(BackendEmulator), StartCandidate(BackendEmulatorBinary)}, skipped); diff != "" {
+		t.Fatalf("skipped candidates mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(decision.Skipped[0].Reason, "did not answer") {
+		t.Fatalf("attach skip reason = %q, want a failed health probe", decision.Skipped[0].Reason)
+	}
+	if len(logs.lines) != 1 || logs.lines[0] != decision.String() {
+		t.Fatalf("logged %q, want the decision %q", logs.lines, decision.String())
+	}
+}
+
+func TestNewLazyRuntimeWithPolicyAttaches(t *testing.T) {
+	clearEndpointEnv(t)
+	server := Setup(t, BackendInProcess, EnableInstanceAutoConfigOnly())
+	t.Setenv(emulatorURIEnv, server.URI())
+
+	runtime := NewLazyRuntimeWithPolicy(RuntimePolicy{
+		Candidates: []RuntimeCandidate{AttachCandidate(BackendEmulator), StartCandidate(BackendInProcess)},
+		Logf:       func(string, ...any) {},
+	})
+	t.Cleanup(func() {
+		if err := runtime.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	clients := SetupClients(t, runtime, WithRandomDatabaseID(), ForceSchemaTeardown(), WithSetupDDLs(inProcessTestDDLs))
+	if got := readSingerNames(t, clients.Client); len(got) != 0 {
+		t.Fatalf("rows = %v, want none", got)
+	}
+	decision, _ := runtime.Decision()
+	if decision.Candidate != AttachCandidate(BackendEmulator) || len(decision.Skipped) != 0 {
+		t.Fatalf("Decision() = %+v, want the attached endpoint without skips", decision)
+	}
+}
+
+func TestNewLazyRuntimeWithPolicyNoCandidate(t *testing.T) {
+	clearEndpointEnv(t)
+	runtime := NewLazyRuntimeWithPolicy(RuntimePolicy{
+		Candidates: []RuntimeCandidate{AttachCandidate(BackendOmni), StartCandidate("no-such-backend")},
+	})
+	t.Cleanup(func() { _ = runtime.Close() })
+
+	_, err := runtime.Get(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "attach omni: no endpoint configured") || !strings.Contains(err.Error(), `unsupported backend "no-such-backend"`) {
+		t.Fatalf("Get() error = %v, want the reason for each candidate", err)
+	}
+	if _, ok := runtime.Decision(); ok {
+		t.Fatal("Decision() reported a decision when no candidate was available")
+	}
+}
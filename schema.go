@@ -0,0 +1,367 @@
+package spanemuboost
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+// Schema is a typed model of the schema of a database, read by
+// [Clients.Schema]. Table names are qualified with their schema when they are
+// not in the default schema, as in "sch.Singers". Tables, indexes, foreign
+// keys, change streams, and sequences are sorted by name.
+type Schema struct {
+	Dialect       databasepb.DatabaseDialect
+	Tables        []*SchemaTable
+	Indexes       []*SchemaIndex
+	ForeignKeys   []*SchemaForeignKey
+	ChangeStreams []*SchemaChangeStream
+	Sequences     []*SchemaSequence
+	// ProtoBundle is nil if the database has no proto bundle.
+	ProtoBundle *SchemaProtoBundle
+}
+
+// SchemaTable describes a table.
+type SchemaTable struct {
+	Name       string
+	Columns    []*SchemaColumn
+	PrimaryKey []string
+	// InterleaveParent is the parent table name, or "" if the table is not
+	// interleaved.
+	InterleaveParent string
+	// OnDeleteAction is "CASCADE" or "NO ACTION" for interleaved tables.
+	OnDeleteAction string
+}
+
+// SchemaColumn describes a column of a table, in the order of the table.
+type SchemaColumn struct {
+	Name string
+	// SpannerType is the type as written in GoogleSQL DDL, such as
+	// "STRING(MAX)", or in PostgreSQL DDL, such as "character varying".
+	SpannerType string
+	Nullable    bool
+	// GenerationExpression is "" unless the column is generated.
+	GenerationExpression string
+	// Default is the default value expression, or "" if there is none.
+	Default string
+}
+
+// SchemaIndex describes a secondary index. Indexes managed by Spanner, such
+// as the backing indexes of foreign keys, are not included.
+type SchemaIndex struct {
+	Name    string
+	Table   string
+	Columns []string
+	Storing []string
+	// InterleaveParent is the table the index is interleaved in, or "".
+	InterleaveParent string
+	Unique           bool
+	NullFiltered     bool
+}
+
+// SchemaForeignKey describes a foreign key constraint.
+type SchemaForeignKey struct {
+	Name              string
+	Table             string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	// OnDelete is the delete rule, such as "CASCADE" or "NO ACTION".
+	OnDelete string
+}
+
+// SchemaChangeStream describes a change stream.
+type SchemaChangeStream struct {
+	Name string
+	// All reports whether the change stream watches the whole database.
+	All    bool
+	Tables []*SchemaChangeStreamTable
+}
+
+// SchemaChangeStreamTable describes a table watched by a change stream.
+type SchemaChangeStreamTable struct {
+	Name       string
+	AllColumns bool
+	// Columns lists the watched non-key columns unless AllColumns is set.
+	Columns []string
+}
+
+// SchemaSequence describes a sequence.
+type SchemaSequence struct {
+	Name     string
+	DataType string
+}
+
+// SchemaProtoBundle describes the proto bundle of a database.
+type SchemaProtoBundle struct {
+	// Types lists the fully qualified proto message and enum types.
+	Types []string
+	// FileDescriptorSet is the serialized FileDescriptorSet of the bundle.
+	FileDescriptorSet []byte
+}
+
+// Table returns the table with the given name, or nil.
+func (s *Schema) Table(name string) *SchemaTable {
+	i := slices.IndexFunc(s.Tables, func(t *SchemaTable) bool { return t.Name == name })
+	if i < 0 {
+		return nil
+	}
+	return s.Tables[i]
+}
+
+// Column returns the column with the given name, or nil.
+func (t *SchemaTable) Column(name string) *SchemaColumn {
+	i := slices.IndexFunc(t.Columns, func(c *SchemaColumn) bool { return c.Name == name })
+	if i < 0 {
+		return nil
+	}
+	return t.Columns[i]
+}
+
+// Schema reads the schema of the database from INFORMATION_SCHEMA in one
+// read-only transaction, and the proto bundle from GetDatabaseDdl. Both the
+// GoogleSQL and PostgreSQL dialects are supported.
+func (c *Clients) Schema(ctx context.Context) (*Schema, error) {
+	if err := c.requireInformationSchema("Clients.Schema"); err != nil {
+		return nil, err
+	}
+	db, err := c.DatabaseClient.GetDatabase(ctx, &databasepb.GetDatabaseRequest{Name: c.DatabasePath()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database %s: %w", c.DatabasePath(), err)
+	}
+	schema, err := loadSchema(ctx, c.Client, db.GetDatabaseDialect())
+	if err != nil {
+		return nil, err
+	}
+	ddl, err := c.DatabaseClient.GetDatabaseDdl(ctx, &databasepb.GetDatabaseDdlRequest{Database: c.DatabasePath()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DDL of database %s: %w", c.DatabasePath(), err)
+	}
+	schema.ProtoBundle = protoBundleFromDDL(ddl.GetStatements(), ddl.GetProtoDescriptors())
+	return schema, nil
+}
+
+func loadSchema(ctx context.Context, client *spanner.Client, dialect databasepb.DatabaseDialect) (*Schema, error) {
+	q := schemaQueries(dialect)
+	schema := &Schema{Dialect: dialect}
+	tables := make(map[string]*SchemaTable)
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	err := queryStrings(ctx, txn, `SELECT table_schema, table_name, parent_table_name, on_delete_action FROM information_schema.tables
+WHERE table_type = 'BASE TABLE' AND `+fixtureSchemaFilter, func(row []string) {
+		t := &SchemaTable{Name: qualifiedTableName(row[0], row[1]), OnDeleteAction: row[3]}
+		if row[2] != "" {
+			t.InterleaveParent = qualifiedTableName(row[0], row[2])
+		}
+		tables[t.Name] = t
+		schema.Tables = append(schema.Tables, t)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read tables: %w", err)
+	}
+
+	err = queryStrings(ctx, txn, `SELECT table_schema, table_name, column_name, spanner_type, is_nullable, generation_expression, column_default
+FROM information_schema.columns WHERE `+fixtureSchemaFilter+` ORDER BY table_schema, table_name, ordinal_position`, func(row []string) {
+		if t, ok := tables[qualifiedTableName(row[0], row[1])]; ok {
+			t.Columns = append(t.Columns, &SchemaColumn{
+				Name:                 row[2],
+				SpannerType:          row[3],
+				Nullable:             row[4] == "YES",
+				GenerationExpression: row[5],
+				Default:              row[6],
+			})
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read columns: %w", err)
+	}
+
+	indexes := make(map[string]*SchemaIndex)
+	err = queryStrings(ctx, txn, `SELECT table_schema, table_name, index_name, parent_table_name, `+q.cast("is_unique")+`, `+q.cast("is_null_filtered")+`
+FROM information_schema.indexes
+WHERE index_type = 'INDEX' AND `+q.cast("spanner_is_managed")+` IN ('false', 'NO') AND `+fixtureSchemaFilter, func(row []string) {
+		idx := &SchemaIndex{
+			Name:         qualifiedTableName(row[0], row[2]),
+			Table:        qualifiedTableName(row[0], row[1]),
+			Unique:       isSchemaTrue(row[4]),
+			NullFiltered: isSchemaTrue(row[5]),
+		}
+		if row[3] != "" {
+			idx.InterleaveParent = qualifiedTableName(row[0], row[3])
+		}
+		indexes[idx.Table+"\x00"+idx.Name] = idx
+		schema.Indexes = append(schema.Indexes, idx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read indexes: %w", err)
+	}
+
+	err = queryStrings(ctx, txn, `SELECT table_schema, table_name, index_name, index_type, column_name,
+  CASE WHEN ordinal_position IS NULL THEN 'STORING' ELSE 'KEY' END
+FROM information_schema.index_columns WHERE `+fixtureSchemaFilter+`
+ORDER BY table_schema, table_name, index_name, ordinal_position, column_name`, func(row []string) {
+		table := qualifiedTableName(row[0], row[1])
+		if row[3] == "PRIMARY_KEY" {
+			if t, ok := tables[table]; ok {
+				t.PrimaryKey = append(t.PrimaryKey, row[4])
+			}
+			return
+		}
+		idx, ok := indexes[table+"\x00"+qualifiedTableName(row[0], row[2])]
+		if !ok {
+			return
+		}
+		if row[5] == "STORING" {
+			idx.Storing = append(idx.Storing, row[4])
+		} else {
+			idx.Columns = append(idx.Columns, row[4])
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read index columns: %w", err)
+	}
+
+	// Columns of every constraint, used to resolve both sides of foreign keys.
+	constraintColumns := make(map[string][]string)
+	err = queryStrings(ctx, txn, `SELECT constraint_schema, constraint_name, column_name FROM information_schema.key_column_usage
+ORDER BY constraint_schema, constraint_name, ordinal_position`, func(row []string) {
+		key := qualifiedTableName(row[0], row[1])
+		constraintColumns[key] = append(constraintColumns[key], row[2])
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read key columns: %w", err)
+	}
+
+	err = queryStrings(ctx, txn, `SELECT tc.constraint_schema, tc.constraint_name, tc.table_schema, tc.table_name,
+  uc.constraint_schema, uc.constraint_name, uc.table_schema, uc.table_name, rc.delete_rule
+FROM information_schema.table_constraints AS tc
+JOIN information_schema.referential_constraints AS rc
+  ON rc.constraint_schema = tc.constraint_schema AND rc.constraint_name = tc.constraint_name
+JOIN information_schema.table_constraints AS uc
+  ON uc.constraint_schema = rc.unique_constraint_schema AND uc.constraint_name = rc.unique_constraint_name
+WHERE tc.constraint_type = 'FOREIGN KEY'`, func(row []string) {
+		name := qualifiedTableName(row[0], row[1])
+		schema.ForeignKeys = append(schema.ForeignKeys, &SchemaForeignKey{
+			Name:              name,
+			Table:             qualifiedTableName(row[2], row[3]),
+			Columns:           constraintColumns[name],
+			ReferencedTable:   qualifiedTableName(row[6], row[7]),
+			ReferencedColumns: constraintColumns[qualifiedTableName(row[4], row[5])],
+			OnDelete:          row[8],
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read foreign keys: %w", err)
+	}
+
+	streams := make(map[string]*SchemaChangeStream)
+	err = queryStrings(ctx, txn, `SELECT change_stream_schema, change_stream_name, `+q.cast(q.allColumn)+` FROM information_schema.change_streams`, func(row []string) {
+		cs := &SchemaChangeStream{Name: qualifiedTableName(row[0], row[1]), All: isSchemaTrue(row[2])}
+		streams[cs.Name] = cs
+		schema.ChangeStreams = append(schema.ChangeStreams, cs)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read change streams: %w", err)
+	}
+
+	streamTables := make(map[string]*SchemaChangeStreamTable)
+	err = queryStrings(ctx, txn, `SELECT change_stream_schema, change_stream_name, table_schema, table_name, `+q.cast("all_columns")+`
+FROM information_schema.change_stream_tables ORDER BY table_schema, table_name`, func(row []string) {
+		cs, ok := streams[qualifiedTableName(row[0], row[1])]
+		if !ok {
+			return
+		}
+		t := &SchemaChangeStreamTable{Name: qualifiedTableName(row[2], row[3]), AllColumns: isSchemaTrue(row[4])}
+		streamTables[cs.Name+"\x00"+t.Name] = t
+		cs.Tables = append(cs.Tables, t)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read change stream tables: %w", err)
+	}
+
+	err = queryStrings(ctx, txn, `SELECT change_stream_schema, change_stream_name, table_schema, table_name, column_name
+FROM information_schema.change_stream_columns ORDER BY column_name`, func(row []string) {
+		if t, ok := streamTables[qualifiedTableName(row[0], row[1])+"\x00"+qualifiedTableName(row[2], row[3])]; ok {
+			t.Columns = append(t.Columns, row[4])
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read change stream columns: %w", err)
+	}
+
+	err = queryStrings(ctx, txn, q.sequences, func(row []string) {
+		schema.Sequences = append(schema.Sequences, &SchemaSequence{Name: qualifiedTableName(row[0], row[1]), DataType: row[2]})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read sequences: %w", err)
+	}
+
+	slices.SortFunc(schema.Tables, func(a, b *SchemaTable) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(schema.Indexes, func(a, b *SchemaIndex) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(schema.ForeignKeys, func(a, b *SchemaForeignKey) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(schema.ChangeStreams, func(a, b *SchemaChangeStream) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(schema.Sequences, func(a, b *SchemaSequence) int { return cmp.Compare(a.Name, b.Name) })
+	return schema, nil
+}
+
+// schemaDialectQueries holds the parts of the INFORMATION_SCHEMA queries that
+// differ between the dialects.
+type schemaDialectQueries struct {
+	stringType string
+	// allColumn is the quoted ALL column of CHANGE_STREAMS, a reserved word.
+	allColumn string
+	sequences string
+}
+
+func schemaQueries(dialect databasepb.DatabaseDialect) schemaDialectQueries {
+	if dialect == databasepb.DatabaseDialect_POSTGRESQL {
+		return schemaDialectQueries{
+			stringType: "varchar",
+			allColumn:  `"all"`,
+			sequences:  `SELECT sequence_schema, sequence_name, data_type FROM information_schema.sequences`,
+		}
+	}
+	return schemaDialectQueries{
+		stringType: "STRING",
+		allColumn:  "`all`",
+		sequences:  `SELECT schema, name, data_type FROM information_schema.sequences`,
+	}
+}
+
+// cast reads a column that is BOOL in GoogleSQL and YES/NO or boolean in
+// PostgreSQL as a string, for use with queryStrings.
+func (q schemaDialectQueries) cast(column string) string {
+	return "CAST(" + column + " AS " + q.stringType + ")"
+}
+
+func isSchemaTrue(s string) bool {
+	return s == "true" || s == "YES"
+}
+
+var protoBundleDDL = regexp.MustCompile(`(?is)^\s*CREATE\s+PROTO\s+BUNDLE\s*\((.*)\)\s*$`)
+
+// protoBundleFromDDL finds the CREATE PROTO BUNDLE statement in ddls, as
+// returned by GetDatabaseDdl, and returns the types it lists.
+func protoBundleFromDDL(ddls []string, descriptors []byte) *SchemaProtoBundle {
+	for _, ddl := range ddls {
+		m := protoBundleDDL.FindStringSubmatch(ddl)
+		if m == nil {
+			continue
+		}
+		bundle := &SchemaProtoBundle{FileDescriptorSet: descriptors}
+		for _, typ := range strings.Split(m[1], ",") {
+			if typ = strings.Trim(strings.TrimSpace(typ), "`"); typ != "" {
+				bundle.Types = append(bundle.Types, typ)
+			}
+		}
+		return bundle
+	}
+	return nil
+}
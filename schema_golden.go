@@ -0,0 +1,130 @@
+package spanemuboost
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+)
+
+const updateGoldenEnv = "SPANEMUBOOST_UPDATE_GOLDEN"
+
+// AssertSchema compares the schema of the database of clients, as returned by
+// GetDatabaseDdl, with the golden DDL file at goldenPath, and reports the
+// difference with [testing.TB.Errorf].
+//
+// The golden file holds one statement per DDL, each terminated by a
+// semicolon, and may contain comments. Statements are compared after
+// normalizing line endings and surrounding whitespace.
+//
+// To create or rewrite the golden file, set SPANEMUBOOST_UPDATE_GOLDEN=1, or
+// run the test with -update.
+//
+// The -update flag must be declared by the test package: spanemuboost cannot
+// register it, because flag panics when a test package that already declares
+// -update for its own golden files is linked with a library that does too.
+// Until it is declared, `go test -update` fails with "flag provided but not
+// defined". Declare it once per test package:
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+// AssertSchema looks the flag up by name, so the variable itself need not be
+// used.
+func AssertSchema(tb testing.TB, clients *Clients, goldenPath string) {
+	tb.Helper()
+
+	got, dialect, err := databaseDDL(tb.Context(), clients)
+	if err != nil {
+		tb.Fatalf("spanemuboost: AssertSchema: %v", err)
+	}
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			tb.Fatalf("spanemuboost: AssertSchema: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, []byte(formatGoldenDDL(got)), 0o644); err != nil {
+			tb.Fatalf("spanemuboost: AssertSchema: %v", err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		tb.Fatalf("spanemuboost: AssertSchema: read golden file: %v (run with -update or %s=1 to create it)", err, updateGoldenEnv)
+	}
+	diff, err := diffGoldenDDL(string(golden), got, dialect)
+	if err != nil {
+		tb.Fatalf("spanemuboost: AssertSchema: parse golden file %q: %v", goldenPath, err)
+	}
+	if diff != "" {
+		tb.Errorf("spanemuboost: schema of %s does not match %s (-want +got):\n%s\nRun with -update or %s=1 to rewrite the golden file.",
+			clients.DatabaseID, goldenPath, diff, updateGoldenEnv)
+	}
+}
+
+func databaseDDL(ctx context.Context, clients *Clients) ([]string, databasepb.DatabaseDialect, error) {
+	schema, err := readDatabaseSchema(ctx, clients.DatabaseClient, clients.DatabasePath())
+	if err != nil {
+		return nil, 0, err
+	}
+	ddls := make([]string, len(schema.ddls))
+	for i, ddl := range schema.ddls {
+		ddls[i] = normalizeDDL(ddl)
+	}
+	return ddls, schema.dialect, nil
+}
+
+// diffGoldenDDL returns the difference between the statements of golden and
+// the normalized statements got, or "" if they match.
+func diffGoldenDDL(golden string, got []string, dialect databasepb.DatabaseDialect) (string, error) {
+	stmts, err := splitStatements(golden, dialect)
+	if err != nil {
+		return "", err
+	}
+	want := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		want[i] = normalizeDDL(stmt.SQL)
+	}
+	return cmp.Diff(want, got), nil
+}
+
+// normalizeDDL normalizes line endings and drops trailing whitespace on each
+// line and around the statement.
+func normalizeDDL(ddl string) string {
+	lines := strings.Split(strings.ReplaceAll(ddl, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func formatGoldenDDL(ddls []string) string {
+	var b strings.Builder
+	for i, ddl := range ddls {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s;\n", ddl)
+	}
+	return b.String()
+}
+
+// updateGolden reports whether golden files should be rewritten, through
+// SPANEMUBOOST_UPDATE_GOLDEN or an -update flag defined by the test package.
+func updateGolden() bool {
+	if v, err := strconv.ParseBool(os.Getenv(updateGoldenEnv)); err == nil && v {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	v, err := strconv.ParseBool(f.Value.String())
+	return err == nil && v
+}
@@ -0,0 +1,98 @@
+package spanemuboost
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+func TestDiffGoldenDDL(t *testing.T) {
+	got := []string{
+		"CREATE TABLE Singers (\n  SingerId INT64 NOT NULL,\n) PRIMARY KEY(SingerId)",
+		"CREATE INDEX SingersById ON Singers(SingerId)",
+	}
+
+	golden := "-- Generated by AssertSchema.\r\n" + formatGoldenDDL(got)
+	diff, err := diffGoldenDDL(golden, got, databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL)
+	if err != nil {
+		t.Fatalf("diffGoldenDDL() error = %v", err)
+	}
+	if diff != "" {
+		t.Fatalf("diffGoldenDDL() = %s, want no difference", diff)
+	}
+
+	diff, err = diffGoldenDDL(formatGoldenDDL(got[:1]), got, databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL)
+	if err != nil {
+		t.Fatalf("diffGoldenDDL() error = %v", err)
+	}
+	if !strings.Contains(diff, "SingersById") {
+		t.Fatalf("diffGoldenDDL() = %q, want the missing index", diff)
+	}
+}
+
+func TestNormalizeDDL(t *testing.T) {
+	got := normalizeDDL("\n  CREATE TABLE t (  \r\n  pk INT64,\t\r\n) PRIMARY KEY(pk)  \n")
+	want := "CREATE TABLE t (\n  pk INT64,\n) PRIMARY KEY(pk)"
+	if got != want {
+		t.Fatalf("normalizeDDL() = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateGoldenEnv(t *testing.T) {
+	t.Setenv(updateGoldenEnv, "1")
+	if !updateGolden() {
+		t.Fatal("updateGolden() = false, want true")
+	}
+	t.Setenv(updateGoldenEnv, "")
+	if updateGolden() {
+		t.Fatal("updateGolden() = true, want false")
+	}
+}
+
+// update is declared as the AssertSchema doc asks test packages to.
+var update = flag.Bool("update", false, "update golden files")
+
+func TestUpdateGoldenFlag(t *testing.T) {
+	t.Setenv(updateGoldenEnv, "")
+	if updateGolden() != *update {
+		t.Fatalf("updateGolden() = %t, want the -update value %t", updateGolden(), *update)
+	}
+
+	old := flag.Lookup("update").Value.String()
+	t.Cleanup(func() { _ = flag.Set("update", old) })
+	for _, value := range []string{"true", "false"} {
+		if err := flag.Set("update", value); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := updateGolden(), value == "true"; got != want {
+			t.Fatalf("updateGolden() with -update=%s = %t, want %t", value, got, want)
+		}
+	}
+}
+
+func TestAssertSchema(t *testing.T) {
+	clients := SetupEmulatorWithClients(t,
+		WithSetupDDLs([]string{
+			"CREATE TABLE Singers (SingerId INT64, Name STRING(MAX)) PRIMARY KEY (SingerId)",
+			"CREATE INDEX SingersByName ON Singers (Name)",
+		}),
+	).Clients
+	golden := filepath.Join(t.TempDir(), "testdata", "schema.sql")
+
+	t.Setenv(updateGoldenEnv, "1")
+	AssertSchema(t, clients, golden)
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("golden file was not written: %v", err)
+	}
+	if !strings.Contains(string(data), "CREATE INDEX SingersByName") {
+		t.Fatalf("golden file = %s, want the index", data)
+	}
+
+	t.Setenv(updateGoldenEnv, "")
+	AssertSchema(t, clients, golden)
+}
@@ -0,0 +1,271 @@
+package spanemuboost
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/api/option"
+)
+
+// WithSchemaTemplates enables the schema template cache of a runtime, keeping
+// up to prewarm databases ready per distinct schema. Pass it to [Run],
+// [Setup], [NewLazyRuntime], [RunEmulator], [SetupEmulator], or
+// [NewAttachedRuntime]; it has no effect on [OpenClients] and [SetupClients].
+//
+// The schema of a database is defined by its dialect, setup DDLs, migrations,
+// proto descriptors, and fixtures. The first [OpenClients] or [SetupClients]
+// call with [WithRandomDatabaseID] for a given schema bootstraps its database
+// as usual and registers the schema as a template. From then on, the runtime
+// creates databases with that schema in the background, and later calls with
+// the same schema take one of them instead of creating and migrating a
+// database themselves. Setup mutations, DMLs, and steps are still applied per
+// call, so each caller gets its own database.
+//
+// Calls that find no prepared database fall back to the regular bootstrap.
+// Prepared databases that are not taken are dropped when the runtime is closed.
+//
+// The prepared databases of all schemas together never exceed half of the
+// runtime's per-instance limit ([WithMaxDatabasesPerInstance], or the emulator
+// default of 100), which leaves the rest for the databases in use. When the
+// cap is reached, preparing further databases waits for prepared ones to be
+// taken.
+func WithSchemaTemplates(prewarm int) Option {
+	return func(opts *emulatorOptions) error {
+		if prewarm <= 0 {
+			return fmt.Errorf("WithSchemaTemplates: prewarm must be > 0, got %d", prewarm)
+		}
+		opts.schemaTemplatePrewarm = prewarm
+		return nil
+	}
+}
+
+// schemaTemplateCache holds the schema templates of one runtime and the
+// background workers that prepare databases for them.
+type schemaTemplateCache struct {
+	prewarm int
+	// prepared bounds the databases that are prepared and not yet taken,
+	// across all templates.
+	prepared *semaphore.Weighted
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu        sync.Mutex
+	closed    bool
+	templates map[string]*schemaTemplate
+}
+
+// schemaTemplate is a cached database definition and the prepared databases
+// created from it.
+type schemaTemplate struct {
+	opts       *emulatorOptions
+	clientOpts []option.ClientOption
+	ready      chan string
+}
+
+// enableSchemaTemplates creates the schema template cache of a runtime if
+// [WithSchemaTemplates] is set in its options.
+func enableSchemaTemplates(opts *emulatorOptions) {
+	if opts.schemaTemplatePrewarm <= 0 || opts.schemaTemplates != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	limit := max(opts.maxDatabasesPerInstance, defaultMaxDatabasesPerInstance)
+	opts.schemaTemplates = &schemaTemplateCache{
+		prewarm:   opts.schemaTemplatePrewarm,
+		prepared:  semaphore.NewWeighted(int64(limit / 2)),
+		ctx:       ctx,
+		cancel:    cancel,
+		templates: make(map[string]*schemaTemplate),
+	}
+}
+
+// schemaTemplateEligible reports whether the database of opts can be taken
+// from, or used to register, a schema template.
+func (o *emulatorOptions) schemaTemplateEligible() bool {
+	return o.schemaTemplates != nil &&
+		o.randomDatabaseID &&
+		!o.randomProjectID &&
+		!o.randomInstanceID &&
+		!o.disableCreateDatabase &&
+		len(o.copiedRows) == 0 &&
+		(o.hasSetupDDLWork() || len(o.setupFixtures) > 0)
+}
+
+// schemaTemplateKey fingerprints the parts of opts that define the schema and
+// the fixture data of a template.
+func schemaTemplateKey(opts *emulatorOptions) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", opts.projectID, opts.instanceID, opts.databaseDialect)
+	for _, ddl := range opts.setupDDLs {
+		fmt.Fprintf(h, "ddl\x00%s\x00", ddl)
+	}
+	for _, m := range opts.migrations {
+		fmt.Fprintf(h, "migration\x00%d\x00", m.version)
+		for _, ddl := range m.ddls {
+			fmt.Fprintf(h, "%s\x00", ddl)
+		}
+	}
+	fmt.Fprintf(h, "descriptors\x00%x\x00", opts.setupFileDescriptorSet)
+	for _, f := range opts.setupFixtures {
+		fmt.Fprintf(h, "fixture\x00%s\x00%s\x00%t\x00%v\x00", f.file, f.table, f.csv, f.rows)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// take returns a prepared database for the template key and reports whether
+// one was available.
+func (c *schemaTemplateCache) take(key string) (string, bool) {
+	c.mu.Lock()
+	t, ok := c.templates[key]
+	c.mu.Unlock()
+	if !ok {
+		return "", false
+	}
+	select {
+	case id := <-t.ready:
+		c.prepared.Release(1)
+		return id, true
+	default:
+		return "", false
+	}
+}
+
+// register records the schema of opts as the template for key, unless one is
+// already registered, and starts preparing databases for it.
+func (c *schemaTemplateCache) register(key string, opts *emulatorOptions, clientOpts []option.ClientOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if _, ok := c.templates[key]; ok {
+		return
+	}
+	t := &schemaTemplate{
+		opts:       schemaOptions(opts),
+		clientOpts: slices.Clone(clientOpts),
+		ready:      make(chan string, c.prewarm),
+	}
+	c.templates[key] = t
+	c.wg.Add(1)
+	go c.fill(t)
+}
+
+// schemaOptions copies the parts of opts that define the schema and fixtures
+// of a database, which are needed to recreate or reset it.
+func schemaOptions(opts *emulatorOptions) *emulatorOptions {
+	config := *opts.clientConfig
+	return &emulatorOptions{
+		projectID:              opts.projectID,
+		instanceID:             opts.instanceID,
+		databaseDialect:        opts.databaseDialect,
+		setupDDLs:              opts.setupDDLs,
+		setupDDLSources:        opts.setupDDLSources,
+		setupFileDescriptorSet: opts.setupFileDescriptorSet,
+		migrations:             opts.migrations,
+		setupFixtures:          opts.setupFixtures,
+		setupBatchSize:         opts.setupBatchSize,
+		clientConfig:           &config,
+		clientOptionsForClient: opts.clientOptionsForClient,
+	}
+}
+
+// fill keeps the prepared databases of t topped up until the cache is closed,
+// then drops the databases that were not taken. If preparing a database
+// fails, fill stops and later calls fall back to the regular bootstrap.
+func (c *schemaTemplateCache) fill(t *schemaTemplate) {
+	defer c.wg.Done()
+
+	dbCli, err := database.NewDatabaseAdminClient(c.ctx, t.clientOpts...)
+	if err != nil {
+		logCloseError("start schema template", err)
+		return
+	}
+	defer func() {
+		for {
+			select {
+			case id := <-t.ready:
+				t.drop(dbCli, id)
+				c.prepared.Release(1)
+			default:
+				logCloseError("close database admin client", dbCli.Close())
+				return
+			}
+		}
+	}()
+
+	for {
+		if err := c.prepared.Acquire(c.ctx, 1); err != nil {
+			return
+		}
+		id, err := t.prepare(c.ctx, dbCli)
+		if err != nil {
+			c.prepared.Release(1)
+			if c.ctx.Err() == nil {
+				logCloseError("prepare schema template database", err)
+			}
+			return
+		}
+		select {
+		case t.ready <- id:
+		case <-c.ctx.Done():
+			t.drop(dbCli, id)
+			c.prepared.Release(1)
+			return
+		}
+	}
+}
+
+// prepare creates a database with a random ID from the template and returns
+// its ID.
+func (t *schemaTemplate) prepare(ctx context.Context, dbCli *database.DatabaseAdminClient) (string, error) {
+	opts := *t.opts
+	opts.databaseID = generateRandomID()
+	created, err := bootstrapDatabase(ctx, &opts, dbCli)
+	if err == nil && !created {
+		err = fmt.Errorf("database %s already exists", opts.DatabasePath())
+	}
+	if err == nil && len(opts.setupFixtures) > 0 {
+		var client *spanner.Client
+		client, err = spanner.NewClientWithConfig(ctx, opts.DatabasePath(), minimalBootstrapClientConfig(*opts.clientConfig), slices.Concat(t.clientOpts, opts.clientOptionsForClient)...)
+		if err == nil {
+			err = applyFixtures(ctx, &opts, client)
+			client.Close()
+		}
+	}
+	if err != nil {
+		if created {
+			t.drop(dbCli, opts.databaseID)
+		}
+		return "", err
+	}
+	return opts.databaseID, nil
+}
+
+// drop drops a prepared database, logging failures.
+func (t *schemaTemplate) drop(dbCli *database.DatabaseAdminClient, id string) {
+	ctx, cancel := newCloseContext()
+	defer cancel()
+	logCloseError("drop prepared database", dropDatabaseWithRetry(ctx, dbCli, databasePath(t.opts.projectID, t.opts.instanceID, id)))
+}
+
+// close stops preparing databases and drops the prepared databases that were
+// not taken. close is nil-safe.
+func (c *schemaTemplateCache) close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.cancel()
+	c.wg.Wait()
+}
@@ -0,0 +1,170 @@
+package spanemuboost
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+func TestWithSchemaTemplates(t *testing.T) {
+	_, err := applyOptions(WithSchemaTemplates(0))
+	if err == nil || !strings.Contains(err.Error(), "WithSchemaTemplates: prewarm must be > 0, got 0") {
+		t.Fatalf("applyOptions() error = %v, want invalid prewarm error", err)
+	}
+
+	opts, err := applyOptions(WithSchemaTemplates(2))
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if opts.schemaTemplates != nil {
+		t.Fatal("applyOptions() created a schema template cache, want none until a runtime enables it")
+	}
+	enableSchemaTemplates(opts)
+	defer opts.schemaTemplates.close()
+	if opts.schemaTemplates == nil || opts.schemaTemplates.prewarm != 2 {
+		t.Fatalf("enableSchemaTemplates() cache = %+v, want prewarm 2", opts.schemaTemplates)
+	}
+
+	emu := &Emulator{opts: opts}
+	inherited, err := emu.inheritedOptions(WithRandomDatabaseID(), WithSetupDDLs([]string{"CREATE TABLE t (pk INT64) PRIMARY KEY (pk)"}))
+	if err != nil {
+		t.Fatalf("inheritedOptions: %v", err)
+	}
+	if inherited.schemaTemplates != opts.schemaTemplates {
+		t.Fatal("inheritedOptions() did not inherit the runtime's schema template cache")
+	}
+	if !inherited.schemaTemplateEligible() {
+		t.Fatal("schemaTemplateEligible() = false, want true")
+	}
+	if _, ok := inherited.schemaTemplates.take(schemaTemplateKey(inherited)); ok {
+		t.Fatal("take() = true for an unregistered template, want false")
+	}
+
+	fixed, err := emu.inheritedOptions(WithSetupDDLs([]string{"CREATE TABLE t (pk INT64) PRIMARY KEY (pk)"}))
+	if err != nil {
+		t.Fatalf("inheritedOptions: %v", err)
+	}
+	if fixed.schemaTemplateEligible() {
+		t.Fatal("schemaTemplateEligible() = true for a fixed database ID, want false")
+	}
+
+	var nilCache *schemaTemplateCache
+	nilCache.close()
+}
+
+func TestSchemaTemplateKey(t *testing.T) {
+	ddl := "CREATE TABLE t (pk INT64) PRIMARY KEY (pk)"
+	key := func(t *testing.T, options ...Option) string {
+		t.Helper()
+		opts, err := applyOptions(options...)
+		if err != nil {
+			t.Fatalf("applyOptions: %v", err)
+		}
+		return schemaTemplateKey(opts)
+	}
+
+	base := key(t, WithRandomDatabaseID(), WithSetupDDLs([]string{ddl}))
+	if got := key(t, WithRandomDatabaseID(), WithSetupDDLs([]string{ddl}),
+		WithSetupRawDMLs([]string{"DELETE FROM t WHERE TRUE"})); got != base {
+		t.Fatal("schemaTemplateKey() depends on the database ID or setup DMLs, want schema only")
+	}
+	for name, options := range map[string][]Option{
+		"DDLs":    {WithSetupDDLs([]string{ddl, "CREATE INDEX i ON t (pk)"})},
+		"dialect": {WithSetupDDLs([]string{ddl}), WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL)},
+		"fixtures": {WithSetupDDLs([]string{ddl}), WithSetupFixtures(fstest.MapFS{
+			"t.csv": {Data: []byte("pk\n1\n")},
+		}, "*.csv")},
+	} {
+		if got := key(t, options...); got == base {
+			t.Errorf("schemaTemplateKey() ignores %s", name)
+		}
+	}
+}
+
+func TestSchemaTemplatesOpenClients(t *testing.T) {
+	emu := SetupEmulator(t, WithSchemaTemplates(1))
+	ddls := []string{"CREATE TABLE tbl (pk INT64, col INT64) PRIMARY KEY (pk)"}
+
+	first := SetupClients(t, emu, WithRandomDatabaseID(), WithSetupDDLs(ddls))
+	if _, err := first.Client.Apply(t.Context(), []*spanner.Mutation{spanner.InsertMap("tbl", map[string]any{"pk": 1, "col": 1})}); err != nil {
+		t.Fatal(err)
+	}
+
+	key := schemaTemplateKey(&emulatorOptions{
+		projectID:  emu.ProjectID(),
+		instanceID: emu.InstanceID(),
+		setupDDLs:  ddls,
+	})
+	cache := emu.opts.schemaTemplates
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		cache.mu.Lock()
+		tmpl := cache.templates[key]
+		cache.mu.Unlock()
+		if tmpl != nil && len(tmpl.ready) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a prepared database")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	second := SetupClients(t, emu,
+		WithRandomDatabaseID(),
+		WithSetupDDLs(ddls),
+		WithSetupRawDMLs([]string{"INSERT INTO tbl (pk, col) VALUES (2, 2)"}),
+	)
+	if second.DatabaseID == first.DatabaseID {
+		t.Fatalf("second DatabaseID = %q, want a database different from the first", second.DatabaseID)
+	}
+
+	var pks []int64
+	err := second.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT pk FROM tbl ORDER BY pk")).Do(func(r *spanner.Row) error {
+		var pk int64
+		if err := r.Column(0, &pk); err != nil {
+			return err
+		}
+		pks = append(pks, pk)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pks) != 1 || pks[0] != 2 {
+		t.Fatalf("pks = %v, want [2] (only the second call's setup DML)", pks)
+	}
+}
+
+func TestSchemaTemplatesCapPreparedDatabases(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    int64
+	}{
+		{name: "default limit", options: []Option{WithSchemaTemplates(1)}, want: defaultMaxDatabasesPerInstance / 2},
+		{name: "raised limit", options: []Option{WithSchemaTemplates(1), WithMaxDatabasesPerInstance(300)}, want: 150},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := applyOptions(tt.options...)
+			if err != nil {
+				t.Fatalf("applyOptions: %v", err)
+			}
+			enableSchemaTemplates(opts)
+			defer opts.schemaTemplates.close()
+
+			prepared := opts.schemaTemplates.prepared
+			if !prepared.TryAcquire(tt.want) {
+				t.Fatalf("prepared.TryAcquire(%d) = false, want true", tt.want)
+			}
+			if prepared.TryAcquire(1) {
+				t.Fatalf("prepared.TryAcquire(1) = true after %d prepared databases, want false", tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package spanemuboost
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestProtoBundleFromDDL(t *testing.T) {
+	got := protoBundleFromDDL([]string{
+		"CREATE TABLE t (pk INT64) PRIMARY KEY (pk)",
+		"CREATE PROTO BUNDLE (\n  `examples.shipping.Order`,\n  examples.shipping.Status\n)",
+	}, []byte{1})
+	want := &SchemaProtoBundle{Types: []string{"examples.shipping.Order", "examples.shipping.Status"}, FileDescriptorSet: []byte{1}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("protoBundleFromDDL() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := protoBundleFromDDL([]string{"CREATE TABLE t (pk INT64) PRIMARY KEY (pk)"}, nil); got != nil {
+		t.Fatalf("protoBundleFromDDL() = %#v, want nil", got)
+	}
+}
+
+func TestClientsSchema(t *testing.T) {
+	clients := SetupEmulatorWithClients(t,
+		WithSetupDDLs([]string{
+			"CREATE SEQUENCE Seq OPTIONS (sequence_kind = 'bit_reversed_positive')",
+			"CREATE TABLE Singers (SingerId INT64 NOT NULL, Name STRING(MAX), Upper STRING(MAX) AS (UPPER(Name)) STORED, Rank INT64 DEFAULT (1)) PRIMARY KEY (SingerId)",
+			"CREATE TABLE Albums (SingerId INT64 NOT NULL, AlbumId INT64 NOT NULL, Title STRING(100)) PRIMARY KEY (SingerId, AlbumId), INTERLEAVE IN PARENT Singers ON DELETE CASCADE",
+			"CREATE TABLE Concerts (ConcertId INT64 NOT NULL, SingerId INT64, CONSTRAINT FK_Singer FOREIGN KEY (SingerId) REFERENCES Singers (SingerId)) PRIMARY KEY (ConcertId)",
+			"CREATE UNIQUE NULL_FILTERED INDEX AlbumsByTitle ON Albums (SingerId, Title) STORING (AlbumId), INTERLEAVE IN Singers",
+			"CREATE CHANGE STREAM SingerNames FOR Singers (Name)",
+		}),
+	).Clients
+
+	got, err := clients.Schema(t.Context())
+	if err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+	want := &Schema{
+		Dialect: databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL,
+		Tables: []*SchemaTable{
+			{
+				Name: "Albums",
+				Columns: []*SchemaColumn{
+					{Name: "SingerId", SpannerType: "INT64"},
+					{Name: "AlbumId", SpannerType: "INT64"},
+					{Name: "Title", SpannerType: "STRING(100)", Nullable: true},
+				},
+				PrimaryKey:       []string{"SingerId", "AlbumId"},
+				InterleaveParent: "Singers",
+				OnDeleteAction:   "CASCADE",
+			},
+			{
+				Name: "Concerts",
+				Columns: []*SchemaColumn{
+					{Name: "ConcertId", SpannerType: "INT64"},
+					{Name: "SingerId", SpannerType: "INT64", Nullable: true},
+				},
+				PrimaryKey: []string{"ConcertId"},
+			},
+			{
+				Name: "Singers",
+				Columns: []*SchemaColumn{
+					{Name: "SingerId", SpannerType: "INT64"},
+					{Name: "Name", SpannerType: "STRING(MAX)", Nullable: true},
+					{Name: "Upper", SpannerType: "STRING(MAX)", Nullable: true, GenerationExpression: "UPPER(Name)"},
+					{Name: "Rank", SpannerType: "INT64", Nullable: true, Default: "1"},
+				},
+				PrimaryKey: []string{"SingerId"},
+			},
+		},
+		Indexes: []*SchemaIndex{{
+			Name:             "AlbumsByTitle",
+			Table:            "Albums",
+			Columns:          []string{"SingerId", "Title"},
+			Storing:          []string{"AlbumId"},
+			InterleaveParent: "Singers",
+			Unique:           true,
+			NullFiltered:     true,
+		}},
+		ForeignKeys: []*SchemaForeignKey{{
+			Name:              "FK_Singer",
+			Table:             "Concerts",
+			Columns:           []string{"SingerId"},
+			ReferencedTable:   "Singers",
+			ReferencedColumns: []string{"SingerId"},
+			OnDelete:          "NO ACTION",
+		}},
+		ChangeStreams: []*SchemaChangeStream{{
+			Name:   "SingerNames",
+			Tables: []*SchemaChangeStreamTable{{Name: "Singers", Columns: []string{"Name"}}},
+		}},
+		Sequences: []*SchemaSequence{{Name: "Seq", DataType: "INT64"}},
+	}
+	// Expressions are normalized by the backend, so only their presence is compared.
+	ignoreExpressions := cmpopts.IgnoreFields(SchemaColumn{}, "GenerationExpression", "Default")
+	if diff := cmp.Diff(want, got, ignoreExpressions); diff != "" {
+		t.Fatalf("Schema() mismatch (-want +got):\n%s", diff)
+	}
+	singers := got.Table("Singers")
+	if singers.Column("Upper").GenerationExpression == "" || singers.Column("Rank").Default == "" {
+		t.Fatalf("Schema() Singers columns = %+v, want generation and default expressions", singers.Columns)
+	}
+}
+
+func TestClientsSchemaPostgreSQL(t *testing.T) {
+	clients := SetupEmulatorWithClients(t,
+		WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL),
+		WithSetupDDLs([]string{
+			"CREATE TABLE singers (singer_id bigint NOT NULL PRIMARY KEY, name varchar)",
+			"CREATE INDEX singers_by_name ON singers (name)",
+		}),
+	).Clients
+
+	got, err := clients.Schema(t.Context())
+	if err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+	if got.Dialect != databasepb.DatabaseDialect_POSTGRESQL {
+		t.Fatalf("Schema().Dialect = %v, want POSTGRESQL", got.Dialect)
+	}
+	singers := got.Table("singers")
+	if singers == nil {
+		t.Fatalf("Schema().Tables = %+v, want singers", got.Tables)
+	}
+	if diff := cmp.Diff([]string{"singer_id"}, singers.PrimaryKey); diff != "" {
+		t.Fatalf("primary key mismatch (-want +got):\n%s", diff)
+	}
+	if len(got.Indexes) != 1 || got.Indexes[0].Name != "singers_by_name" || got.Indexes[0].Unique {
+		t.Fatalf("Schema().Indexes = %+v, want non-unique singers_by_name", got.Indexes)
+	}
+}
@@ -2,26 +2,53 @@ package spanemuboost
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 )
 
+const defaultStateSaveTimeout = time.Minute
+
 // Serve starts a backend runtime, writes its [Endpoint] metadata when endpointPath
 // is non-empty, and blocks until ctx is canceled. The runtime is closed before
 // Serve returns. When an endpoint file was written, it is removed on exit so
 // stale metadata is not left behind.
 func Serve(ctx context.Context, backend Backend, endpointPath string, options ...Option) error {
+	return serve(ctx, ServeConfig{Backend: backend, EndpointFile: endpointPath, Options: options})
+}
+
+func serve(ctx context.Context, cfg ServeConfig) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	runtime, err := Run(ctx, backend, options...)
+	options := cfg.Options
+	var state *Dump
+	if cfg.StateFile != "" {
+		var err error
+		state, err = readStateFile(cfg.StateFile)
+		if err != nil {
+			return err
+		}
+		if state != nil {
+			// The saved databases are restored below, so the default database
+			// is not created again.
+			options = append(slices.Clone(options), disableDatabaseAutoConfig())
+		}
+	}
+	runtime, err := Run(ctx, cfg.Backend, options...)
 	if err != nil {
 		return err
 	}
 	defer func() {
 		logCloseError("close runtime after serve", runtime.Close())
 	}()
+	if state != nil {
+		if err := LoadRuntime(ctx, runtime, state); err != nil {
+			return fmt.Errorf("spanemuboost: restore state file %q: %w", cfg.StateFile, err)
+		}
+	}
 
 	endpoint, err := EndpointFromRuntime(runtime)
 	if err != nil {
@@ -30,22 +57,55 @@ func Serve(ctx context.Context, backend Backend, endpointPath string, options ..
 	endpoint.ManagedBy = "spanemuboost serve"
 	endpoint.PID = os.Getpid()
 	endpoint.StartedAt = time.Now().UTC().Format(time.RFC3339)
-	if endpointPath != "" {
-		if err := SaveEndpoint(endpointPath, endpoint); err != nil {
+	if cfg.EndpointFile != "" {
+		if err := SaveEndpoint(cfg.EndpointFile, endpoint); err != nil {
 			return err
 		}
 		defer func() {
-			if err := os.Remove(endpointPath); err != nil && !os.IsNotExist(err) {
+			if err := os.Remove(cfg.EndpointFile); err != nil && !os.IsNotExist(err) {
 				logCloseError("remove endpoint file after serve", err)
 			}
 		}()
 	}
 
 	<-ctx.Done()
+	var stateErr error
+	if cfg.StateFile != "" {
+		stateErr = saveStateFile(context.WithoutCancel(ctx), runtime, cfg.StateFile)
+	}
 	if err := ctx.Err(); err != nil && err != context.Canceled {
-		return err
+		return errors.Join(err, stateErr)
+	}
+	return stateErr
+}
+
+// readStateFile reads the state file of serve. It returns nil if the file
+// does not exist yet.
+func readStateFile(path string) (*Dump, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return ReadDumpFile(path)
+}
+
+func saveStateFile(ctx context.Context, runtime Runtime, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultStateSaveTimeout)
+	defer cancel()
+	dump, err := DumpRuntime(ctx, runtime)
+	if err != nil {
+		return fmt.Errorf("spanemuboost: save state file %q: %w", path, err)
+	}
+	return WriteDumpFile(path, dump)
+}
+
+// disableDatabaseAutoConfig disables database auto-creation and keeps
+// instance auto-creation as configured.
+func disableDatabaseAutoConfig() Option {
+	return func(opts *emulatorOptions) error {
+		opts.disableCreateDatabase = true
+		opts.reuseExistingDatabase = false
+		return nil
 	}
-	return nil
 }
 
 // ServeConfig configures [ServeFromConfig].
@@ -53,7 +113,11 @@ type ServeConfig struct {
 	Backend      Backend
 	EndpointFile string
 	PIDFile      string
-	Options      []Option
+	// StateFile, when set, is loaded with [LoadRuntime] on startup if it
+	// exists, and the databases are saved to it with [DumpRuntime] on
+	// shutdown.
+	StateFile string
+	Options   []Option
 }
 
 // ServeFromConfig starts a backend and blocks until interrupted.
@@ -68,10 +132,11 @@ func ServeFromConfig(ctx context.Context, cfg ServeConfig) error {
 			}
 		}()
 	}
-	return Serve(ctx, cfg.Backend, cfg.EndpointFile, cfg.Options...)
+	return serve(ctx, cfg)
 }
 
-// ParseServeArgs parses `spanemuboost serve <emulator|omni> --endpoint-file path [--pid-file path] [--with-default-database]`.
+// ParseServeArgs parses `spanemuboost serve <emulator|omni> --endpoint-file path [--pid-file path] [--state-file path] [--with-default-database]`.
+// A backend registered with [RegisterBackend] is accepted in place of emulator or omni.
 func ParseServeArgs(args []string) (ServeConfig, error) {
 	cfg := ServeConfig{}
 	var backend string
@@ -90,6 +155,12 @@ func ParseServeArgs(args []string) (ServeConfig, error) {
 			}
 			cfg.PIDFile = args[i+1]
 			i++
+		case "--state-file":
+			if i+1 >= len(args) {
+				return ServeConfig{}, fmt.Errorf("--state-file requires a value")
+			}
+			cfg.StateFile = args[i+1]
+			i++
 		case "--with-default-database":
 			withDefaultDatabase = true
 		case "emulator", "omni":
@@ -98,11 +169,17 @@ func ParseServeArgs(args []string) (ServeConfig, error) {
 			}
 			backend = args[i]
 		default:
-			return ServeConfig{}, fmt.Errorf("unknown argument %q", args[i])
+			if _, ok := lookupBackendProvider(Backend(args[i])); !ok {
+				return ServeConfig{}, fmt.Errorf("unknown argument %q", args[i])
+			}
+			if backend != "" {
+				return ServeConfig{}, fmt.Errorf("multiple backends specified: %q and %q", backend, args[i])
+			}
+			backend = args[i]
 		}
 	}
 	if backend == "" {
-		return ServeConfig{}, fmt.Errorf("usage: spanemuboost serve <emulator|omni> --endpoint-file path [--pid-file path] [--with-default-database]")
+		return ServeConfig{}, fmt.Errorf("usage: spanemuboost serve <emulator|omni> --endpoint-file path [--pid-file path] [--state-file path] [--with-default-database]")
 	}
 	switch Backend(backend) {
 	case BackendEmulator:
@@ -113,6 +190,10 @@ func ParseServeArgs(args []string) (ServeConfig, error) {
 			cfg.Options = append(cfg.Options, DisableAutoConfig())
 		}
 	default:
+		if _, ok := lookupBackendProvider(Backend(backend)); ok {
+			cfg.Backend = Backend(backend)
+			break
+		}
 		return ServeConfig{}, fmt.Errorf("unsupported serve backend %q; supported values are emulator and omni", backend)
 	}
 	return cfg, nil
@@ -0,0 +1,108 @@
+package spanemuboost
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+)
+
+// DefaultSetupBatchSize is the default maximum number of setup DML statements
+// or mutations applied in one transaction.
+const DefaultSetupBatchSize = 1000
+
+// WithSetupBatchSize sets the maximum number of setup DML statements, or of
+// setup fixture rows and mutations, applied in one read-write transaction.
+// Larger seeds are split across several transactions, which keeps them under
+// Spanner's per-commit mutation limit. The default is [DefaultSetupBatchSize].
+//
+// Each transaction is atomic, but setup data as a whole is not: when a chunk
+// fails, the chunks before it stay committed.
+func WithSetupBatchSize(n int) Option {
+	return func(opts *emulatorOptions) error {
+		if n <= 0 {
+			return fmt.Errorf("WithSetupBatchSize: n must be > 0, got %d", n)
+		}
+		opts.setupBatchSize = n
+		return nil
+	}
+}
+
+// WithSetupDMLBatches sets DMLs to be executed with explicit transaction
+// boundaries: each batch starts a new read-write transaction. A batch larger
+// than [WithSetupBatchSize] is still split.
+// Calling this multiple times replaces the previous value.
+// This is mutually exclusive with [WithSetupDMLs], [WithSetupRawDMLs], and
+// [WithSetupDMLFiles]; the last one called wins.
+func WithSetupDMLBatches(batches ...[]spanner.Statement) Option {
+	return func(opts *emulatorOptions) error {
+		opts.setupDMLs = nil
+		opts.setupDMLBoundaries = nil
+		for _, batch := range batches {
+			opts.setupDMLBoundaries = append(opts.setupDMLBoundaries, len(opts.setupDMLs))
+			opts.setupDMLs = append(opts.setupDMLs, batch...)
+		}
+		opts.setupDMLSources = nil
+		opts.setupDMLFiles = nil
+		return nil
+	}
+}
+
+// WithSetupMutationBatches adds mutations with explicit transaction
+// boundaries: each batch is applied in its own transaction, separately from
+// mutations added before or after it. A batch larger than
+// [WithSetupBatchSize] is still split.
+// Calling this multiple times appends to the previous value, as do
+// [WithSetupMutations] and [WithSetupStructs].
+func WithSetupMutationBatches(batches ...[]*spanner.Mutation) Option {
+	return func(opts *emulatorOptions) error {
+		for _, batch := range batches {
+			opts.setupMutationBoundaries = append(opts.setupMutationBoundaries, len(opts.setupMutations))
+			opts.setupMutations = append(opts.setupMutations, batch...)
+		}
+		opts.setupMutationBoundaries = append(opts.setupMutationBoundaries, len(opts.setupMutations))
+		return nil
+	}
+}
+
+func (o *emulatorOptions) setupBatchSizeOrDefault() int {
+	if o.setupBatchSize > 0 {
+		return o.setupBatchSize
+	}
+	return DefaultSetupBatchSize
+}
+
+// setupChunk is the half-open index range [start, end) of one transaction.
+type setupChunk struct {
+	start, end int
+}
+
+// setupChunks splits n items into chunks of at most size items, also starting
+// a new chunk at each of the ascending boundaries.
+func setupChunks(n, size int, boundaries []int) []setupChunk {
+	var chunks []setupChunk
+	for start := 0; start < n; {
+		end := min(start+size, n)
+		for _, b := range boundaries {
+			if b > start && b < end {
+				end = b
+				break
+			}
+		}
+		chunks = append(chunks, setupChunk{start: start, end: end})
+		start = end
+	}
+	return chunks
+}
+
+// applyMutationChunks applies mutations in chunked transactions. what names
+// the mutations in error messages.
+func applyMutationChunks(ctx context.Context, client *spanner.Client, what string, mutations []*spanner.Mutation, size int, boundaries []int) error {
+	chunks := setupChunks(len(mutations), size, boundaries)
+	for i, c := range chunks {
+		if _, err := client.Apply(ctx, mutations[c.start:c.end]); err != nil {
+			return fmt.Errorf("failed to apply %s at indexes %d-%d (chunk %d of %d): %w", what, c.start, c.end-1, i+1, len(chunks), err)
+		}
+	}
+	return nil
+}
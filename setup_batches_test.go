@@ -0,0 +1,148 @@
+package spanemuboost
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetupChunks(t *testing.T) {
+	tests := []struct {
+		name       string
+		n, size    int
+		boundaries []int
+		want       []setupChunk
+	}{
+		{name: "empty", n: 0, size: 2, want: nil},
+		{name: "single chunk", n: 3, size: 10, want: []setupChunk{{0, 3}}},
+		{name: "split by size", n: 5, size: 2, want: []setupChunk{{0, 2}, {2, 4}, {4, 5}}},
+		{name: "split by boundaries", n: 5, size: 10, boundaries: []int{0, 1, 3, 5}, want: []setupChunk{{0, 1}, {1, 3}, {3, 5}}},
+		{name: "boundaries and size", n: 7, size: 3, boundaries: []int{1}, want: []setupChunk{{0, 1}, {1, 4}, {4, 7}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := setupChunks(tt.n, tt.size, tt.boundaries)
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(setupChunk{})); diff != "" {
+				t.Fatalf("setupChunks() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithSetupDMLBatches(t *testing.T) {
+	stmt := spanner.NewStatement("UPDATE t SET c = 1 WHERE TRUE")
+	opts, err := applyOptions(
+		WithSetupRawDMLs([]string{"DELETE FROM t WHERE TRUE"}),
+		WithSetupDMLBatches([]spanner.Statement{stmt, stmt}, nil, []spanner.Statement{stmt}),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if got, want := len(opts.setupDMLs), 3; got != want {
+		t.Fatalf("len(setupDMLs) = %d, want %d", got, want)
+	}
+	if diff := cmp.Diff([]int{0, 2, 2}, opts.setupDMLBoundaries); diff != "" {
+		t.Fatalf("setupDMLBoundaries mismatch (-want +got):\n%s", diff)
+	}
+
+	opts, err = applyOptions(
+		WithSetupDMLBatches([]spanner.Statement{stmt}),
+		WithSetupDMLs([]spanner.Statement{stmt}),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if opts.setupDMLBoundaries != nil {
+		t.Fatalf("setupDMLBoundaries = %v, want nil after WithSetupDMLs", opts.setupDMLBoundaries)
+	}
+}
+
+func TestWithSetupMutationBatches(t *testing.T) {
+	m := spanner.Delete("t", spanner.AllKeys())
+	opts, err := applyOptions(
+		WithSetupMutations([]*spanner.Mutation{m}),
+		WithSetupMutationBatches([]*spanner.Mutation{m, m}),
+		WithSetupMutations([]*spanner.Mutation{m}),
+		WithSetupBatchSize(10),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	got := setupChunks(len(opts.setupMutations), opts.setupBatchSizeOrDefault(), opts.setupMutationBoundaries)
+	want := []setupChunk{{0, 1}, {1, 3}, {3, 4}}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(setupChunk{})); diff != "" {
+		t.Fatalf("chunks mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithSetupBatchSize(t *testing.T) {
+	opts, err := applyOptions()
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if got := opts.setupBatchSizeOrDefault(); got != DefaultSetupBatchSize {
+		t.Fatalf("setupBatchSizeOrDefault() = %d, want %d", got, DefaultSetupBatchSize)
+	}
+
+	_, err = applyOptions(WithSetupBatchSize(0))
+	if err == nil || !strings.Contains(err.Error(), "WithSetupBatchSize: n must be > 0, got 0") {
+		t.Fatalf("applyOptions() error = %v, want invalid batch size error", err)
+	}
+}
+
+func TestSetupDMLChunks(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	ddls := []string{"CREATE TABLE tbl (pk INT64, col INT64 NOT NULL) PRIMARY KEY (pk)"}
+
+	t.Run("applies every chunk", func(t *testing.T) {
+		clients := SetupClients(t, emu,
+			WithRandomDatabaseID(),
+			WithSetupDDLs(ddls),
+			WithSetupBatchSize(2),
+			WithSetupRawDMLs([]string{
+				"INSERT INTO tbl (pk, col) VALUES (1, 1)",
+				"INSERT INTO tbl (pk, col) VALUES (2, 2)",
+				"INSERT INTO tbl (pk, col) VALUES (3, 3)",
+			}),
+		)
+		var count int64
+		err := clients.Client.Single().Query(t.Context(), spanner.NewStatement("SELECT COUNT(*) FROM tbl")).Do(func(r *spanner.Row) error {
+			return r.Column(0, &count)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 {
+			t.Fatalf("COUNT(*) = %d, want 3", count)
+		}
+	})
+
+	t.Run("reports failing chunk and index", func(t *testing.T) {
+		_, err := OpenClients(t.Context(), emu,
+			WithRandomDatabaseID(),
+			WithSetupDDLs(ddls),
+			WithSetupBatchSize(2),
+			WithSetupRawDMLs([]string{
+				"INSERT INTO tbl (pk, col) VALUES (1, 1)",
+				"INSERT INTO tbl (pk, col) VALUES (2, 2)",
+				"INSERT INTO tbl (pk, col) VALUES (3, NULL)",
+			}),
+		)
+		if err == nil {
+			t.Fatal("OpenClients() error = nil, want DML failure")
+		}
+		var stmtErr *SetupStatementError
+		if !errors.As(err, &stmtErr) {
+			t.Fatalf("OpenClients() error = %v, want *SetupStatementError", err)
+		}
+		if stmtErr.Stage != SetupStageDML || stmtErr.Index != 2 || stmtErr.Statement != "INSERT INTO tbl (pk, col) VALUES (3, NULL)" {
+			t.Fatalf("SetupStatementError = %+v, want DML statement 2", stmtErr)
+		}
+		if stmtErr.Chunk != 2 || stmtErr.Chunks != 2 || !strings.Contains(err.Error(), "(chunk 2 of 2)") {
+			t.Fatalf("OpenClients() error = %v (chunk %d of %d), want chunk 2 of 2", err, stmtErr.Chunk, stmtErr.Chunks)
+		}
+	})
+}
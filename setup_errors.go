@@ -0,0 +1,101 @@
+package spanemuboost
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetupStage identifies the bootstrap stage reported by a [SetupStatementError].
+type SetupStage string
+
+const (
+	// SetupStageCreateDatabase is the CreateDatabase operation, which also
+	// applies setup DDLs when no migrations are configured. If one of them
+	// fails, the database is created again without them and they are applied
+	// in SetupStageDDL, so that the failing statement is reported.
+	SetupStageCreateDatabase SetupStage = "create database"
+	// SetupStageDDL is an UpdateDatabaseDdl operation applying migrations,
+	// setup DDLs, or a [DDLStep].
+	SetupStageDDL SetupStage = "DDL"
+	// SetupStageDML is a BatchUpdate executing setup DMLs or a [DMLStep].
+	SetupStageDML SetupStage = "DML"
+)
+
+// SetupStatementError reports the setup statement that failed during
+// bootstrap. Use [errors.As] to retrieve it from errors returned by
+// [OpenClients], [SetupClients], and the other bootstrapping functions.
+type SetupStatementError struct {
+	// Stage is the bootstrap stage that failed.
+	Stage SetupStage
+	// Index is the index of the failing statement in the full statement list
+	// being applied, such as the setup DDLs, the DDLs of one migration file,
+	// the setup DMLs across all chunks, or the statements of one step. It is
+	// -1 if the statement cannot be determined, and always for
+	// SetupStageCreateDatabase.
+	Index int
+	// Chunk is the 1-based number of the transaction that failed among Chunks
+	// transactions when DMLs are chunked by [WithSetupBatchSize] or
+	// [WithSetupDMLBatches], or zero for other stages.
+	Chunk, Chunks int
+	// Statement is the text of the failing statement, or empty if Index is -1.
+	Statement string
+	// Source is the file:line of the failing statement if it was loaded from
+	// a file, or empty otherwise.
+	Source string
+	// Err is the underlying error returned by Spanner.
+	Err error
+}
+
+func (e *SetupStatementError) Error() string {
+	var b strings.Builder
+	if e.Stage == SetupStageCreateDatabase {
+		b.WriteString("failed to create database")
+	} else {
+		fmt.Fprintf(&b, "failed to apply %s", e.Stage)
+	}
+	if e.Index >= 0 {
+		fmt.Fprintf(&b, " statement %d", e.Index)
+	}
+	if e.Chunks > 0 {
+		fmt.Fprintf(&b, " (chunk %d of %d)", e.Chunk, e.Chunks)
+	}
+	if e.Source != "" {
+		fmt.Fprintf(&b, " at %s", e.Source)
+	}
+	if e.Statement != "" {
+		fmt.Fprintf(&b, " (%s)", abbreviateStatement(e.Statement))
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+func (e *SetupStatementError) Unwrap() error {
+	return e.Err
+}
+
+// maxStatementSummaryLength bounds the statement text in error messages.
+const maxStatementSummaryLength = 80
+
+// abbreviateStatement collapses whitespace in stmt and truncates it for error
+// messages. The full text is available in [SetupStatementError.Statement].
+func abbreviateStatement(stmt string) string {
+	s := strings.Join(strings.Fields(stmt), " ")
+	if r := []rune(s); len(r) > maxStatementSummaryLength {
+		return string(r[:maxStatementSummaryLength]) + "..."
+	}
+	return s
+}
+
+// newSetupStatementError returns a [SetupStatementError] for statement i with
+// text stmt, or for an unknown statement if i is negative. sources optionally
+// holds the file location of each statement.
+func newSetupStatementError(stage SetupStage, i int, stmt string, sources []string, err error) *SetupStatementError {
+	if i < 0 {
+		return &SetupStatementError{Stage: stage, Index: -1, Err: err}
+	}
+	e := &SetupStatementError{Stage: stage, Index: i, Statement: stmt, Err: err}
+	if i < len(sources) {
+		e.Source = sources[i]
+	}
+	return e
+}
@@ -0,0 +1,93 @@
+package spanemuboost
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetupStatementError(t *testing.T) {
+	errBoom := errors.New("boom")
+	sources := []string{"a.sql:1", "a.sql:3"}
+	tests := []struct {
+		name string
+		err  *SetupStatementError
+		want string
+	}{
+		{
+			name: "with source",
+			err:  newSetupStatementError(SetupStageDDL, 1, "CREATE TABLE t (\n  pk INT64\n) PRIMARY KEY (pk)", sources, errBoom),
+			want: "failed to apply DDL statement 1 at a.sql:3 (CREATE TABLE t ( pk INT64 ) PRIMARY KEY (pk)): boom",
+		},
+		{
+			name: "without source",
+			err:  newSetupStatementError(SetupStageDML, 2, "DELETE FROM t WHERE TRUE", sources, errBoom),
+			want: "failed to apply DML statement 2 (DELETE FROM t WHERE TRUE): boom",
+		},
+		{
+			name: "chunked DML",
+			err:  &SetupStatementError{Stage: SetupStageDML, Index: 4, Chunk: 3, Chunks: 5, Statement: "DELETE FROM t WHERE TRUE", Source: "seed.sql:9", Err: errBoom},
+			want: "failed to apply DML statement 4 (chunk 3 of 5) at seed.sql:9 (DELETE FROM t WHERE TRUE): boom",
+		},
+		{
+			name: "unknown statement",
+			err:  newSetupStatementError(SetupStageCreateDatabase, -1, "", sources, errBoom),
+			want: "failed to create database: boom",
+		},
+		{
+			name: "long statement",
+			err:  newSetupStatementError(SetupStageDML, 0, "INSERT INTO t (c) VALUES ('"+strings.Repeat("x", 100)+"')", nil, errBoom),
+			want: "failed to apply DML statement 0 (INSERT INTO t (c) VALUES ('" + strings.Repeat("x", 53) + "...): boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Fatalf("Error() = %q, want %q", got, tt.want)
+			}
+			if !errors.Is(tt.err, errBoom) {
+				t.Fatalf("errors.Is(%v, errBoom) = false, want true", tt.err)
+			}
+		})
+	}
+
+	if e := newSetupStatementError(SetupStageDDL, -1, "ignored", sources, errBoom); e.Index != -1 || e.Statement != "" || e.Source != "" {
+		t.Fatalf("newSetupStatementError(-1) = %+v, want unknown statement", e)
+	}
+}
+
+func TestSetupStatementErrorStages(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	valid := "CREATE TABLE tbl (pk INT64) PRIMARY KEY (pk)"
+	invalid := "ALTER TABLE missing ADD COLUMN c INT64"
+
+	t.Run("auto-created database", func(t *testing.T) {
+		_, err := OpenClients(t.Context(), emu,
+			WithRandomDatabaseID(),
+			WithSetupDDLs([]string{valid, invalid}),
+		)
+		var stmtErr *SetupStatementError
+		if !errors.As(err, &stmtErr) {
+			t.Fatalf("OpenClients() error = %v, want *SetupStatementError", err)
+		}
+		if stmtErr.Stage != SetupStageDDL || stmtErr.Index != 1 || stmtErr.Statement != invalid {
+			t.Fatalf("SetupStatementError = %+v, want DDL statement 1", stmtErr)
+		}
+	})
+
+	t.Run("DDL on existing database", func(t *testing.T) {
+		clients := SetupClients(t, emu, WithRandomDatabaseID())
+		_, err := OpenClients(t.Context(), emu,
+			DisableAutoConfig(),
+			WithDatabaseID(clients.DatabaseID),
+			WithSetupDDLs([]string{valid, invalid}),
+		)
+		var stmtErr *SetupStatementError
+		if !errors.As(err, &stmtErr) {
+			t.Fatalf("OpenClients() error = %v, want *SetupStatementError", err)
+		}
+		if stmtErr.Stage != SetupStageDDL || stmtErr.Index != 1 || stmtErr.Statement != invalid {
+			t.Fatalf("SetupStatementError = %+v, want DDL statement 1", stmtErr)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package spanemuboost
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+)
+
+// setupFile is a file read by a setup option such as WithSetupDDLFiles.
+// Splitting is deferred to option finalization so that it can honor the final
+// database dialect regardless of option order.
+type setupFile struct {
+	name    string
+	content string
+}
+
+// WithSetupDDLFiles sets DDLs to be executed from files in fsys.
+//
+// Each pattern is matched with [fs.Glob]. Files are read pattern by pattern,
+// in lexical order within a pattern; a file matched by several patterns is
+// read once, at its first match. Every pattern must match at least one file.
+//
+// File contents are split into statements on top-level semicolons with a
+// built-in splitter that strips comments and understands the string literal
+// and quoted identifier syntax of the configured [WithDatabaseDialect].
+// Statements that fail to split or to apply are reported as file:line.
+//
+// Calling this multiple times replaces the previous value.
+// This is mutually exclusive with [WithSetupDDLs]; the last one called wins.
+func WithSetupDDLFiles(fsys fs.FS, patterns ...string) Option {
+	return func(opts *emulatorOptions) error {
+		files, err := readSetupFiles(fsys, patterns)
+		if err != nil {
+			return fmt.Errorf("WithSetupDDLFiles: %w", err)
+		}
+		opts.setupDDLs = nil
+		opts.setupDDLSources = nil
+		opts.setupDDLFiles = files
+		return nil
+	}
+}
+
+// WithSetupDMLFiles sets DMLs to be executed from files in fsys.
+//
+// Files are selected and split the same way as [WithSetupDDLFiles].
+//
+// Calling this multiple times replaces the previous value.
+// This is mutually exclusive with [WithSetupDMLs], [WithSetupRawDMLs], and
+// [WithSetupDMLBatches]; the last one called wins.
+func WithSetupDMLFiles(fsys fs.FS, patterns ...string) Option {
+	return func(opts *emulatorOptions) error {
+		files, err := readSetupFiles(fsys, patterns)
+		if err != nil {
+			return fmt.Errorf("WithSetupDMLFiles: %w", err)
+		}
+		opts.setupDMLs = nil
+		opts.setupDMLBoundaries = nil
+		opts.setupDMLSources = nil
+		opts.setupDMLFiles = files
+		return nil
+	}
+}
+
+func readSetupFiles(fsys fs.FS, patterns []string) ([]setupFile, error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("fs.FS is nil")
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("at least one pattern is required")
+	}
+	var names []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("pattern %q matched no files", pattern)
+		}
+		slices.Sort(matches)
+		for _, name := range matches {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	files := make([]setupFile, 0, len(names))
+	for _, name := range names {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		files = append(files, setupFile{name: name, content: string(content)})
+	}
+	return files, nil
+}
+
+// splitSQLFiles splits files into statements and returns the statement texts
+// along with a parallel slice of file:line sources.
+func splitSQLFiles(files []setupFile, opts *emulatorOptions) ([]string, []string, error) {
+	var stmts, sources []string
+	for _, file := range files {
+		split, err := splitStatements(file.content, opts.databaseDialect)
+		if err != nil {
+			var syntaxErr *syntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, nil, fmt.Errorf("%s:%d: %s", file.name, syntaxErr.line, syntaxErr.msg)
+			}
+			return nil, nil, fmt.Errorf("%s: %w", file.name, err)
+		}
+		for _, stmt := range split {
+			stmts = append(stmts, stmt.SQL)
+			sources = append(sources, fmt.Sprintf("%s:%d", file.name, stmt.Line))
+		}
+	}
+	return stmts, sources, nil
+}
+
+// resolveSetupFiles converts pending setup SQL files into setup statements.
+// It is idempotent so that options can be finalized again when inherited.
+func resolveSetupFiles(opts *emulatorOptions) error {
+	if len(opts.setupDDLFiles) > 0 {
+		stmts, sources, err := splitSQLFiles(opts.setupDDLFiles, opts)
+		if err != nil {
+			return fmt.Errorf("WithSetupDDLFiles: %w", err)
+		}
+		opts.setupDDLs = stmts
+		opts.setupDDLSources = sources
+		opts.setupDDLFiles = nil
+	}
+	if len(opts.setupDMLFiles) > 0 {
+		stmts, sources, err := splitSQLFiles(opts.setupDMLFiles, opts)
+		if err != nil {
+			return fmt.Errorf("WithSetupDMLFiles: %w", err)
+		}
+		dmls := make([]spanner.Statement, 0, len(stmts))
+		for _, stmt := range stmts {
+			dmls = append(dmls, spanner.NewStatement(stmt))
+		}
+		opts.setupDMLs = dmls
+		opts.setupDMLSources = sources
+		opts.setupDMLFiles = nil
+	}
+	return resolveMigrations(opts)
+}
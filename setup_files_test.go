@@ -0,0 +1,191 @@
+package spanemuboost
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithSetupDDLFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema/002_index.sql": {Data: []byte("CREATE INDEX idx ON tbl (col);\n")},
+		"schema/001_init.sql": {Data: []byte(`-- tables
+CREATE TABLE tbl (
+  pk STRING(MAX),
+  col INT64,
+) PRIMARY KEY (pk);
+
+CREATE TABLE other (pk INT64) PRIMARY KEY (pk);
+`)},
+		"extra.sql": {Data: []byte("CREATE TABLE extra (pk INT64) PRIMARY KEY (pk)")},
+	}
+
+	opts, err := applyOptions(WithSetupDDLFiles(fsys, "schema/*.sql", "schema/001_init.sql", "extra.sql"))
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+
+	wantDDLs := []string{
+		"CREATE TABLE tbl (\n  pk STRING(MAX),\n  col INT64,\n) PRIMARY KEY (pk)",
+		"CREATE TABLE other (pk INT64) PRIMARY KEY (pk)",
+		"CREATE INDEX idx ON tbl (col)",
+		"CREATE TABLE extra (pk INT64) PRIMARY KEY (pk)",
+	}
+	if diff := cmp.Diff(wantDDLs, opts.setupDDLs); diff != "" {
+		t.Fatalf("setupDDLs mismatch (-want +got):\n%s", diff)
+	}
+	wantSources := []string{
+		"schema/001_init.sql:2",
+		"schema/001_init.sql:7",
+		"schema/002_index.sql:1",
+		"extra.sql:1",
+	}
+	if diff := cmp.Diff(wantSources, opts.setupDDLSources); diff != "" {
+		t.Fatalf("setupDDLSources mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithSetupDMLFilesUsesFinalDialect(t *testing.T) {
+	fsys := fstest.MapFS{
+		"seed.sql": {Data: []byte(`INSERT INTO t (s) VALUES ($$a;b$$); INSERT INTO t (s) VALUES ('c')`)},
+	}
+
+	// The dialect is configured after the files, so splitting must be deferred.
+	opts, err := applyOptions(
+		WithSetupDMLFiles(fsys, "seed.sql"),
+		WithDatabaseDialect(databasepb.DatabaseDialect_POSTGRESQL),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	var got []string
+	for _, stmt := range opts.setupDMLs {
+		got = append(got, stmt.SQL)
+	}
+	want := []string{
+		"INSERT INTO t (s) VALUES ($$a;b$$)",
+		"INSERT INTO t (s) VALUES ('c')",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("setupDMLs mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"seed.sql:1", "seed.sql:1"}, opts.setupDMLSources); diff != "" {
+		t.Fatalf("setupDMLSources mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetupFileOptionsLastWins(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema.sql": {Data: []byte("CREATE TABLE a (pk INT64) PRIMARY KEY (pk)")},
+		"seed.sql":   {Data: []byte("INSERT INTO a (pk) VALUES (1)")},
+	}
+
+	opts, err := applyOptions(
+		WithSetupDDLFiles(fsys, "schema.sql"),
+		WithSetupDDLs([]string{"CREATE TABLE b (pk INT64) PRIMARY KEY (pk)"}),
+		WithSetupDMLFiles(fsys, "seed.sql"),
+		WithSetupRawDMLs([]string{"INSERT INTO b (pk) VALUES (1)"}),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if len(opts.setupDDLs) != 1 || !strings.Contains(opts.setupDDLs[0], "TABLE b") {
+		t.Fatalf("setupDDLs = %q, want WithSetupDDLs value", opts.setupDDLs)
+	}
+	if opts.setupDDLSources != nil || opts.setupDMLSources != nil {
+		t.Fatalf("sources = %q / %q, want nil", opts.setupDDLSources, opts.setupDMLSources)
+	}
+	if len(opts.setupDMLs) != 1 || !strings.Contains(opts.setupDMLs[0].SQL, "INTO b") {
+		t.Fatalf("setupDMLs = %v, want WithSetupRawDMLs value", opts.setupDMLs)
+	}
+
+	opts, err = applyOptions(
+		WithSetupDDLs([]string{"CREATE TABLE b (pk INT64) PRIMARY KEY (pk)"}),
+		WithSetupDDLFiles(fsys, "schema.sql"),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if diff := cmp.Diff([]string{"CREATE TABLE a (pk INT64) PRIMARY KEY (pk)"}, opts.setupDDLs); diff != "" {
+		t.Fatalf("setupDDLs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetupFileOptionsReportErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ok.sql":     {Data: []byte("SELECT 1")},
+		"broken.sql": {Data: []byte("CREATE TABLE a (pk INT64) PRIMARY KEY (pk);\n\nINSERT INTO a (s) VALUES ('open")},
+	}
+	tests := []struct {
+		name string
+		opt  Option
+		want string
+	}{
+		{name: "no match", opt: WithSetupDDLFiles(fsys, "missing/*.sql"), want: `pattern "missing/*.sql" matched no files`},
+		{name: "bad pattern", opt: WithSetupDMLFiles(fsys, "["), want: `pattern "["`},
+		{name: "no patterns", opt: WithSetupDDLFiles(fsys), want: "at least one pattern is required"},
+		{name: "nil fs", opt: WithSetupDDLFiles(nil, "*.sql"), want: "fs.FS is nil"},
+		{name: "syntax error location", opt: WithSetupDMLFiles(fsys, "broken.sql"), want: "WithSetupDMLFiles: broken.sql:3: unterminated string literal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyOptions(tt.opt)
+			if err == nil {
+				t.Fatal("applyOptions() error = nil, want non-nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("applyOptions() error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachedRuntimeInheritedOptionsPreservesSetupSources(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema.sql": {Data: []byte("CREATE TABLE a (pk INT64) PRIMARY KEY (pk)")},
+	}
+	runtime, err := NewAttachedRuntime(Endpoint{
+		Backend:    BackendEmulator,
+		URI:        "127.0.0.1:9010",
+		ProjectID:  DefaultProjectID,
+		InstanceID: DefaultInstanceID,
+	}, WithRandomDatabaseID(), WithSetupDDLFiles(fsys, "schema.sql"))
+	if err != nil {
+		t.Fatalf("NewAttachedRuntime() error = %v", err)
+	}
+
+	inherited, err := runtime.inheritedOptions(WithRandomDatabaseID())
+	if err != nil {
+		t.Fatalf("inheritedOptions() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"schema.sql:1"}, inherited.setupDDLSources); diff != "" {
+		t.Fatalf("setupDDLSources mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithSetupDDLFilesReportsSourceOnNewDatabase(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema.sql": {Data: []byte(`CREATE TABLE tbl (pk INT64) PRIMARY KEY (pk);
+
+ALTER TABLE missing ADD COLUMN c INT64;
+`)},
+	}
+	runtime := Setup(t, BackendInProcess, EnableInstanceAutoConfigOnly())
+
+	// The database is created by the default auto-config, not beforehand.
+	_, err := OpenClients(t.Context(), runtime,
+		WithRandomDatabaseID(),
+		WithSetupDDLFiles(fsys, "schema.sql"),
+	)
+	var stmtErr *SetupStatementError
+	if !errors.As(err, &stmtErr) {
+		t.Fatalf("OpenClients() error = %v, want *SetupStatementError", err)
+	}
+	if stmtErr.Source != "schema.sql:3" || !strings.Contains(err.Error(), "schema.sql:3") {
+		t.Fatalf("OpenClients() error = %v, want the failing statement at schema.sql:3", err)
+	}
+}
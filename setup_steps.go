@@ -0,0 +1,129 @@
+package spanemuboost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"google.golang.org/api/option"
+)
+
+// Step is one step of an ordered setup sequence configured by [WithSetupSteps].
+// Use [DDLStep], [DMLStep], [MutationStep], or [FuncStep] to create a Step.
+type Step struct {
+	// kind names the step in error messages.
+	kind string
+	run  func(ctx context.Context, opts *emulatorOptions, clients *Clients) error
+}
+
+const stepKindDDL = "DDL"
+
+// DDLStep returns a [Step] that applies ddls in one UpdateDatabaseDdl
+// operation. Descriptors set by [WithSetupFileDescriptorSet] are sent along,
+// so the statements may create or alter proto bundles.
+func DDLStep(ddls ...string) Step {
+	return Step{kind: stepKindDDL, run: func(ctx context.Context, opts *emulatorOptions, clients *Clients) error {
+		if len(ddls) == 0 {
+			return nil
+		}
+		return updateDDLStatements(ctx, opts, clients.DatabaseClient, ddls, nil)
+	}}
+}
+
+// DMLStep returns a [Step] that executes stmts in a read-write transaction,
+// split into transactions of up to [WithSetupBatchSize] statements.
+func DMLStep(stmts ...spanner.Statement) Step {
+	return Step{kind: "DML", run: func(ctx context.Context, opts *emulatorOptions, clients *Clients) error {
+		return executeDMLChunks(ctx, clients.Client, stmts, nil, opts.setupBatchSizeOrDefault(), nil)
+	}}
+}
+
+// MutationStep returns a [Step] that applies mutations, split into
+// transactions of up to [WithSetupBatchSize] mutations.
+func MutationStep(mutations ...*spanner.Mutation) Step {
+	return Step{kind: "mutation", run: func(ctx context.Context, opts *emulatorOptions, clients *Clients) error {
+		return applyMutationChunks(ctx, clients.Client, "mutations", mutations, opts.setupBatchSizeOrDefault(), nil)
+	}}
+}
+
+// FuncStep returns a [Step] that calls f with clients connected to the
+// database being set up. f must not close the clients.
+func FuncStep(f func(ctx context.Context, clients *Clients) error) Step {
+	return Step{kind: "func", run: func(ctx context.Context, _ *emulatorOptions, clients *Clients) error {
+		if f == nil {
+			return fmt.Errorf("func is nil")
+		}
+		return f(ctx, clients)
+	}}
+}
+
+// WithSetupSteps adds setup steps that are executed in order after the rest
+// of the setup: DDLs and migrations, then fixtures, mutations, and DMLs.
+// Steps can interleave schema changes with data changes, for example to
+// backfill a column before adding a NOT NULL or CHECK constraint to it.
+//
+// Like setup DMLs, steps are executed whenever the runtime bootstraps a
+// database, including an existing database when database creation is
+// disabled. If a step fails, resources created by [OpenClients] and
+// [SetupClients] are rolled back as for any other setup failure.
+//
+// Calling this multiple times appends to the previous value.
+func WithSetupSteps(steps ...Step) Option {
+	return func(opts *emulatorOptions) error {
+		opts.setupSteps = append(opts.setupSteps, steps...)
+		return nil
+	}
+}
+
+func (o *emulatorOptions) hasDDLSetupStep() bool {
+	return slices.ContainsFunc(o.setupSteps, func(step Step) bool { return step.kind == stepKindDDL })
+}
+
+// runSetupSteps executes the setup steps with clients.
+func runSetupSteps(ctx context.Context, opts *emulatorOptions, clients *Clients) error {
+	for i, step := range opts.setupSteps {
+		if step.run == nil {
+			return fmt.Errorf("setup step %d: use DDLStep, DMLStep, MutationStep, or FuncStep to create steps", i)
+		}
+		if err := step.run(ctx, opts, clients); err != nil {
+			return fmt.Errorf("setup step %d (%s): %w", i, step.kind, err)
+		}
+	}
+	return nil
+}
+
+// runSetupStepsWithTemporaryClients executes the setup steps for bootstrap
+// paths that do not hand [Clients] to the caller, using short-lived clients.
+func runSetupStepsWithTemporaryClients(ctx context.Context, opts *emulatorOptions, clientOpts []option.ClientOption, clientConfig spanner.ClientConfig) (retErr error) {
+	clients := &Clients{
+		ProjectID:  opts.projectID,
+		InstanceID: opts.instanceID,
+		DatabaseID: opts.databaseID,
+		clientOpts: clientOpts,
+		inProcess:  opts.inProcess,
+	}
+	defer func() {
+		if err := clients.Close(); err != nil {
+			retErr = errors.Join(retErr, err)
+		}
+	}()
+
+	var err error
+	clients.InstanceClient, err = instance.NewInstanceAdminClient(ctx, clientOpts...)
+	if err != nil {
+		return err
+	}
+	clients.DatabaseClient, err = database.NewDatabaseAdminClient(ctx, clientOpts...)
+	if err != nil {
+		return err
+	}
+	clients.Client, err = spanner.NewClientWithConfig(ctx, opts.DatabasePath(), clientConfig, clientOpts...)
+	if err != nil {
+		return err
+	}
+	return runSetupSteps(ctx, opts, clients)
+}
@@ -0,0 +1,152 @@
+package spanemuboost
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithSetupSteps(t *testing.T) {
+	var got []string
+	record := func(name string) Step {
+		return FuncStep(func(context.Context, *Clients) error {
+			got = append(got, name)
+			return nil
+		})
+	}
+
+	opts, err := applyOptions(
+		WithSetupSteps(record("a"), record("b")),
+		WithSetupSteps(record("c")),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if err := runSetupSteps(t.Context(), opts, &Clients{}); err != nil {
+		t.Fatalf("runSetupSteps() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"a", "b", "c"}, got); diff != "" {
+		t.Fatalf("step order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunSetupStepsErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name  string
+		steps []Step
+		want  string
+	}{
+		{
+			name: "failing step",
+			steps: []Step{
+				FuncStep(func(context.Context, *Clients) error { return nil }),
+				FuncStep(func(context.Context, *Clients) error { return errBoom }),
+			},
+			want: "setup step 1 (func): boom",
+		},
+		{
+			name:  "nil func",
+			steps: []Step{FuncStep(nil)},
+			want:  "setup step 0 (func): func is nil",
+		},
+		{
+			name:  "zero step",
+			steps: []Step{{}},
+			want:  "setup step 0: use DDLStep, DMLStep, MutationStep, or FuncStep to create steps",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := applyOptions(WithSetupSteps(tt.steps...))
+			if err != nil {
+				t.Fatalf("applyOptions: %v", err)
+			}
+			err = runSetupSteps(t.Context(), opts, &Clients{})
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("runSetupSteps() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	opts, err := applyOptions(WithSetupSteps(FuncStep(func(context.Context, *Clients) error { return errBoom })))
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if err := runSetupSteps(t.Context(), opts, &Clients{}); !errors.Is(err, errBoom) {
+		t.Fatalf("runSetupSteps() error = %v, want wrapped %v", err, errBoom)
+	}
+}
+
+func TestValidateSetupFileDescriptorSetWithDDLStep(t *testing.T) {
+	opts, err := applyOptions(
+		DisableAutoConfig(),
+		WithSetupRawFileDescriptorSet([]byte{0x0a, 0x00}),
+		WithSetupSteps(DDLStep("CREATE PROTO BUNDLE (`examples.Item`)")),
+	)
+	if err != nil {
+		t.Fatalf("applyOptions: %v", err)
+	}
+	if err := validateSetupFileDescriptorSet(opts); err != nil {
+		t.Fatalf("validateSetupFileDescriptorSet() error = %v, want nil", err)
+	}
+}
+
+func TestSetupClientsWithSetupSteps(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+
+	t.Run("interleaves schema and data changes", func(t *testing.T) {
+		var count int64
+		clients := SetupClients(t, emu,
+			WithRandomDatabaseID(),
+			WithSetupDDLs([]string{"CREATE TABLE tbl (pk INT64, col INT64) PRIMARY KEY (pk)"}),
+			WithSetupRawDMLs([]string{"INSERT INTO tbl (pk) VALUES (1)"}),
+			WithSetupSteps(
+				MutationStep(spanner.InsertMap("tbl", map[string]any{"pk": 2})),
+				DMLStep(spanner.NewStatement("UPDATE tbl SET col = pk * 10 WHERE col IS NULL")),
+				DDLStep("ALTER TABLE tbl ALTER COLUMN col INT64 NOT NULL"),
+				FuncStep(func(ctx context.Context, clients *Clients) error {
+					return clients.Client.Single().Query(ctx, spanner.NewStatement("SELECT COUNT(*) FROM tbl")).Do(func(r *spanner.Row) error {
+						return r.Column(0, &count)
+					})
+				}),
+			),
+		)
+		if count != 2 {
+			t.Fatalf("COUNT(*) in FuncStep = %d, want 2", count)
+		}
+
+		_, err := clients.Client.Apply(t.Context(), []*spanner.Mutation{spanner.InsertMap("tbl", map[string]any{"pk": 3})})
+		if err == nil {
+			t.Fatal("Apply() error = nil, want NOT NULL violation")
+		}
+	})
+
+	t.Run("failing step rolls back the database", func(t *testing.T) {
+		const databaseID = "setup-steps-rollback"
+		_, err := OpenClients(t.Context(), emu,
+			WithDatabaseID(databaseID),
+			WithSetupDDLs([]string{"CREATE TABLE tbl (pk INT64) PRIMARY KEY (pk)"}),
+			WithSetupSteps(DMLStep(spanner.NewStatement("INSERT INTO missing (pk) VALUES (1)"))),
+		)
+		if err == nil {
+			t.Fatal("OpenClients() error = nil, want setup step failure")
+		}
+		if want := "setup step 0 (DML)"; !strings.Contains(err.Error(), want) {
+			t.Fatalf("OpenClients() error = %q, want substring %q", err, want)
+		}
+
+		_, err = OpenClients(t.Context(), emu,
+			DisableAutoConfig(),
+			WithDatabaseID(databaseID),
+			WithSetupDDLs([]string{"CREATE TABLE tbl2 (pk INT64) PRIMARY KEY (pk)"}),
+		)
+		if err == nil {
+			t.Fatal("OpenClients() error = nil, want missing database after rollback")
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package spanemuboost
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sharedRuntimeDirEnv = "SPANEMUBOOST_SHARED_RUNTIME_DIR"
+
+	sharedRuntimeLockFile     = "lock"
+	sharedRuntimeEndpointFile = "endpoint.json"
+	sharedRuntimeLeaseDir     = "leases"
+	sharedRuntimeManagedBy    = "spanemuboost shared runtime"
+
+	sharedRuntimePollInterval = 200 * time.Millisecond
+
+	defaultSharedRuntimeReleaseTimeout = 5 * time.Minute
+)
+
+// SharedRuntimeConfig configures [NewSharedLazyRuntime].
+type SharedRuntimeConfig struct {
+	// Dir holds the state of shared runtimes. Empty means
+	// SPANEMUBOOST_SHARED_RUNTIME_DIR, or spanemuboost-shared in
+	// [os.TempDir].
+	Dir string
+	// Name identifies the shared runtime within Dir, so that processes with
+	// different options can share different runtimes. Empty means the
+	// backend name.
+	Name string
+	// ReleaseTimeout bounds how long the process that started the runtime
+	// waits in [LazyRuntime.Close] for the other processes to release it.
+	// After the timeout, Close closes the runtime anyway and reports an error.
+	// Zero means 5 minutes.
+	ReleaseTimeout time.Duration
+}
+
+// NewSharedLazyRuntime creates a [LazyRuntime] that shares one runtime of
+// backend across processes, such as the package test binaries of
+// `go test ./...`, instead of starting one per process.
+//
+// On first use, the process takes a file lock in the shared directory. The
+// first process starts the runtime and writes its [Endpoint] file; later
+// processes attach to it as [NewAttachedRuntime] does. Each process holds a
+// lease until [LazyRuntime.Close]. The process that started the runtime waits
+// in Close until every other lease is released, up to
+// SharedRuntimeConfig.ReleaseTimeout, and then closes the runtime, so pair it
+// with [LazyRuntime.TestMain]. Processes that arrive while it waits attach to
+// the runtime and are waited for as well. Each lease file stays locked while its process
+// holds it, so the leases and endpoints of processes that exited without Close
+// are detected by their released locks and discarded.
+//
+// Options apply as for [NewLazyRuntime] in the starting process and as for
+// [NewAttachedRuntime] in attaching processes, so every process should pass
+// the same options; use Name to separate differently configured runtimes.
+// Shared runtimes need a unix host for file locking.
+func NewSharedLazyRuntime(backend Backend, config SharedRuntimeConfig, options ...Option) *LazyRuntime {
+	dir := cmp.Or(config.Dir, os.Getenv(sharedRuntimeDirEnv), filepath.Join(os.TempDir(), "spanemuboost-shared"))
+	return &LazyRuntime{
+		backend: backend,
+		opts:    options,
+		shared: &sharedRuntimeState{
+			dir:            filepath.Join(dir, cmp.Or(config.Name, string(backend))),
+			name:           cmp.Or(config.Name, string(backend)),
+			releaseTimeout: cmp.Or(config.ReleaseTimeout, defaultSharedRuntimeReleaseTimeout),
+		},
+	}
+}
+
+type sharedRuntimeState struct {
+	dir            string
+	name           string
+	releaseTimeout time.Duration
+
+	// Set by acquire during initialization of the lazy runtime. lease stays
+	// open and locked until release.
+	lease *os.File
+	owner bool
+
+	releaseOnce sync.Once
+	releaseErr  error
+}
+
+// acquire attaches to the live runtime in s.dir, or starts one and publishes
+// its endpoint, and registers a lease for this process.
+func (s *sharedRuntimeState) acquire(ctx context.Context, backend Backend, options []Option) (runtimeInstance, error) {
+	if s.name == "." || s.name == ".." || strings.ContainsAny(s.name, `/\`) {
+		return nil, fmt.Errorf("spanemuboost: shared runtime name %q must not be a path", s.name)
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, sharedRuntimeLeaseDir), 0o700); err != nil {
+		return nil, fmt.Errorf("spanemuboost: create shared runtime directory: %w", err)
+	}
+	unlock, err := lockSharedRuntime(ctx, s.dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := pruneSharedRuntimeLeases(s.dir); err != nil {
+		return nil, err
+	}
+	endpointPath := filepath.Join(s.dir, sharedRuntimeEndpointFile)
+	endpoint, err := ReadEndpointFile(endpointPath)
+	if err == nil && hasSharedRuntimeLease(s.dir, endpoint.PID) {
+		runtime, err := NewAttachedRuntime(endpoint, options...)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.writeLease(); err != nil {
+			return nil, errors.Join(err, runtime.Close())
+		}
+		return runtime, nil
+	}
+	// The endpoint is missing, unreadable, or left behind by a process that
+	// exited without Close, whose lease was pruned above.
+	if err := os.Remove(endpointPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("spanemuboost: remove stale shared runtime endpoint: %w", err)
+	}
+
+	runtime, err := Run(ctx, backend, options...)
+	if err != nil {
+		return nil, err
+	}
+	instance, ok := runtime.(runtimeInstance)
+	if !ok {
+		return nil, errors.Join(fmt.Errorf("spanemuboost: shared runtime backend %q returned unexpected runtime type %T", backend, runtime), runtime.Close())
+	}
+	endpoint, err = EndpointFromRuntime(runtime)
+	if err != nil {
+		return nil, errors.Join(err, runtime.Close())
+	}
+	endpoint.ManagedBy = sharedRuntimeManagedBy
+	endpoint.PID = os.Getpid()
+	endpoint.StartedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := SaveEndpoint(endpointPath, endpoint); err != nil {
+		return nil, errors.Join(err, runtime.Close())
+	}
+	if err := s.writeLease(); err != nil {
+		return nil, errors.Join(err, os.Remove(endpointPath), runtime.Close())
+	}
+	s.owner = true
+	return instance, nil
+}
+
+// release drops the lease of this process and calls closeRuntime. The
+// process that started the runtime first waits for the other leases and
+// withdraws the endpoint so that no process attaches while it closes. It keeps
+// its own lease until then, so that processes arriving while it waits attach
+// to the runtime instead of replacing its endpoint.
+func (s *sharedRuntimeState) release(closeRuntime func() error) error {
+	s.releaseOnce.Do(func() {
+		if s.owner {
+			s.releaseErr = s.waitForLeases()
+		}
+		s.releaseErr = errors.Join(s.releaseErr, s.dropLease(), closeRuntime())
+	})
+	return s.releaseErr
+}
+
+// dropLease removes and unlocks the lease of this process, if it holds one.
+func (s *sharedRuntimeState) dropLease() error {
+	if s.lease == nil {
+		return nil
+	}
+	var err error
+	if rmErr := os.Remove(s.lease.Name()); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) {
+		err = fmt.Errorf("spanemuboost: remove shared runtime lease: %w", rmErr)
+	}
+	// Closing the file releases its lock.
+	err = errors.Join(err, s.lease.Close())
+	s.lease = nil
+	return err
+}
+
+// waitForLeases waits until no other process holds a lease, or until
+// s.releaseTimeout elapses, and then withdraws the endpoint and drops the
+// lease of this process under the shared runtime lock.
+func (s *sharedRuntimeState) waitForLeases() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.releaseTimeout)
+	defer cancel()
+	logged := false
+	for {
+		// Once the timeout has passed, take the lock once more to withdraw
+		// the endpoint regardless of the remaining leases.
+		unlock, err := lockSharedRuntime(context.WithoutCancel(ctx), s.dir)
+		if err != nil {
+			return err
+		}
+		if err := pruneSharedRuntimeLeases(s.dir); err != nil {
+			unlock()
+			return err
+		}
+		entries, err := os.ReadDir(filepath.Join(s.dir, sharedRuntimeLeaseDir))
+		if err != nil {
+			unlock()
+			return fmt.Errorf("spanemuboost: read shared runtime leases: %w", err)
+		}
+		own := filepath.Base(s.lease.Name())
+		leases := slices.DeleteFunc(entries, func(e fs.DirEntry) bool { return e.Name() == own })
+		if len(leases) == 0 || ctx.Err() != nil {
+			var err error
+			if rmErr := os.Remove(filepath.Join(s.dir, sharedRuntimeEndpointFile)); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) {
+				err = fmt.Errorf("spanemuboost: remove shared runtime endpoint: %w", rmErr)
+			}
+			err = errors.Join(err, s.dropLease())
+			unlock()
+			if err != nil {
+				return err
+			}
+			if len(leases) > 0 {
+				return fmt.Errorf("spanemuboost: other processes still held leases on shared runtime %q after %v; closing it anyway", s.name, s.releaseTimeout)
+			}
+			return nil
+		}
+		unlock()
+		if !logged {
+			log.Printf("spanemuboost: waiting for other processes to release shared runtime %q (%d leases)", s.name, len(leases))
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(sharedRuntimePollInterval):
+		}
+	}
+}
+
+// writeLease creates the lease file of this process and keeps it locked until
+// release. It must be called with the shared runtime lock held, so that
+// pruneSharedRuntimeLeases never sees the file before it is locked.
+func (s *sharedRuntimeState) writeLease() error {
+	f, err := os.CreateTemp(filepath.Join(s.dir, sharedRuntimeLeaseDir), strconv.Itoa(os.Getpid())+"-*")
+	if err != nil {
+		return fmt.Errorf("spanemuboost: write shared runtime lease: %w", err)
+	}
+	locked, err := tryLockFile(f)
+	if err == nil && !locked {
+		err = errors.New("lease is locked by another process")
+	}
+	if err != nil {
+		return errors.Join(fmt.Errorf("spanemuboost: lock shared runtime lease: %w", err), f.Close(), os.Remove(f.Name()))
+	}
+	s.lease = f
+	return nil
+}
+
+// pruneSharedRuntimeLeases removes the leases of exited processes. A live
+// process keeps its lease file locked, and the lock is released when the
+// process exits, so a lease whose lock can be taken is stale. Unlike PIDs,
+// locks cannot be confused by a reused PID.
+func pruneSharedRuntimeLeases(dir string) error {
+	leaseDir := filepath.Join(dir, sharedRuntimeLeaseDir)
+	entries, err := os.ReadDir(leaseDir)
+	if err != nil {
+		return fmt.Errorf("spanemuboost: read shared runtime leases: %w", err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(leaseDir, entry.Name())
+		stale, err := sharedRuntimeLeaseStale(path)
+		if err != nil {
+			return err
+		}
+		if !stale {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("spanemuboost: remove stale shared runtime lease: %w", err)
+		}
+	}
+	return nil
+}
+
+func sharedRuntimeLeaseStale(path string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("spanemuboost: open shared runtime lease: %w", err)
+	}
+	defer f.Close()
+	locked, err := tryLockFile(f)
+	if err != nil {
+		return false, fmt.Errorf("spanemuboost: lock shared runtime lease: %w", err)
+	}
+	return locked, nil
+}
+
+// hasSharedRuntimeLease reports whether a lease of process pid remains in dir.
+// Call it after pruneSharedRuntimeLeases, so that only live leases remain.
+func hasSharedRuntimeLease(dir string, pid int) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, sharedRuntimeLeaseDir, strconv.Itoa(pid)+"-*"))
+	return pid > 0 && len(matches) > 0
+}
+
+// lockSharedRuntime takes the exclusive lock of dir, polling until ctx is
+// done so that a process starting the runtime does not block cancellation.
+func lockSharedRuntime(ctx context.Context, dir string) (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(dir, sharedRuntimeLockFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("spanemuboost: open shared runtime lock: %w", err)
+	}
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("spanemuboost: lock shared runtime: %w", err)
+		}
+		if locked {
+			return func() {
+				logCloseError("unlock shared runtime", unlockFile(f))
+				logCloseError("close shared runtime lock", f.Close())
+			}, nil
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, fmt.Errorf("spanemuboost: lock shared runtime: %w", context.Cause(ctx))
+		case <-time.After(sharedRuntimePollInterval):
+		}
+	}
+}
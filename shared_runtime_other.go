@@ -0,0 +1,18 @@
+//go:build !unix
+
+package spanemuboost
+
+import (
+	"errors"
+	"os"
+)
+
+var errSharedRuntimeUnsupported = errors.New("shared runtimes need a unix host")
+
+func tryLockFile(*os.File) (bool, error) {
+	return false, errSharedRuntimeUnsupported
+}
+
+func unlockFile(*os.File) error {
+	return errSharedRuntimeUnsupported
+}
@@ -0,0 +1,252 @@
+//go:build unix
+
+package spanemuboost
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+// sharedRuntimeChildEnv makes TestSharedRuntimeChild act as another test
+// process attaching to the shared runtime in the named directory.
+const sharedRuntimeChildEnv = "SPANEMUBOOST_TEST_SHARED_RUNTIME_CHILD"
+
+func TestSharedRuntimeChild(t *testing.T) {
+	dir := os.Getenv(sharedRuntimeChildEnv)
+	if dir == "" {
+		t.Skip("helper process for TestNewSharedLazyRuntime")
+	}
+
+	runtime := NewSharedLazyRuntime(BackendInProcess, SharedRuntimeConfig{Dir: dir})
+	defer func() {
+		if err := runtime.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	started, err := runtime.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := started.(*AttachedRuntime); !ok {
+		t.Fatalf("Get() = %T, want an attached runtime", started)
+	}
+
+	clients := SetupClients(t, runtime, WithRandomDatabaseID(), ForceSchemaTeardown(), WithSetupDDLs(inProcessTestDDLs))
+	if _, err := clients.Client.Apply(t.Context(), []*spanner.Mutation{
+		spanner.Insert("Singers", []string{"SingerId", "Name"}, []any{int64(1), "Child"}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"Child"}, readSingerNames(t, clients.Client)); diff != "" {
+		t.Fatalf("rows mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func sharedRuntimeLeases(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(dir, sharedRuntimeLeaseDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// lockedLease creates a lease file named name in leaseDir and locks it, as a
+// live process holding the lease does. Closing the file releases the lease.
+func lockedLease(t *testing.T, leaseDir, name string) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(leaseDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	if locked, err := tryLockFile(f); err != nil || !locked {
+		t.Fatalf("tryLockFile() = %t, %v; want the lock", locked, err)
+	}
+	return f
+}
+
+// exitedPID returns the PID of a process that has already exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestNewSharedLazyRuntime(t *testing.T) {
+	dir := t.TempDir()
+	owner := NewSharedLazyRuntime(BackendInProcess, SharedRuntimeConfig{Dir: dir})
+	started, err := owner.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := started.(*inProcessRuntime); !ok {
+		t.Fatalf("Get() = %T, want the started runtime", started)
+	}
+	sharedDir := filepath.Join(dir, string(BackendInProcess))
+	endpointPath := filepath.Join(sharedDir, sharedRuntimeEndpointFile)
+	endpoint, err := ReadEndpointFile(endpointPath)
+	if err != nil || endpoint.PID != os.Getpid() || endpoint.URI != started.URI() {
+		t.Fatalf("endpoint file = %+v, %v; want this process serving %s", endpoint, err, started.URI())
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSharedRuntimeChild$", "-test.v")
+	cmd.Env = append(os.Environ(), sharedRuntimeChildEnv+"="+dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "--- PASS: TestSharedRuntimeChild") {
+		t.Fatalf("child process error = %v, output:\n%s", err, out)
+	}
+	if leases := sharedRuntimeLeases(t, sharedDir); len(leases) != 1 || !strings.HasPrefix(leases[0], fmt.Sprintf("%d-", os.Getpid())) {
+		t.Fatalf("leases after the child exited = %v, want only this process", leases)
+	}
+
+	if err := owner.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(endpointPath); !os.IsNotExist(err) {
+		t.Fatalf("endpoint file after Close: Stat() error = %v, want not exist", err)
+	}
+}
+
+func TestSharedRuntimeOwnerWaitsForLeases(t *testing.T) {
+	dir := t.TempDir()
+	owner := NewSharedLazyRuntime(BackendInProcess, SharedRuntimeConfig{Dir: dir, Name: "wait"})
+	if _, err := owner.Get(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	leaseDir := filepath.Join(dir, "wait", sharedRuntimeLeaseDir)
+	// An unlocked lease is stale even if its PID, here reused by this
+	// process, is alive.
+	stale := filepath.Join(leaseDir, fmt.Sprintf("%d-stale", os.Getpid()))
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	live := lockedLease(t, leaseDir, fmt.Sprintf("%d-live", exitedPID(t)))
+	time.AfterFunc(time.Second, func() { _ = live.Close() })
+
+	start := time.Now()
+	if err := owner.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Fatalf("Close() returned after %v, want it to wait for the live lease", elapsed)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("stale lease: Stat() error = %v, want not exist", err)
+	}
+}
+
+func TestSharedRuntimeAttachesWhileOwnerWaits(t *testing.T) {
+	dir := t.TempDir()
+	config := SharedRuntimeConfig{Dir: dir, Name: "draining"}
+	owner := NewSharedLazyRuntime(BackendInProcess, config)
+	started, err := owner.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := lockedLease(t, filepath.Join(dir, "draining", sharedRuntimeLeaseDir), fmt.Sprintf("%d-live", exitedPID(t)))
+
+	ownerClosed := make(chan error, 1)
+	go func() { ownerClosed <- owner.Close() }()
+	time.Sleep(2 * sharedRuntimePollInterval)
+
+	// Another process arrives while the owner waits for the live lease.
+	newcomer := NewSharedLazyRuntime(BackendInProcess, config)
+	attached, err := newcomer.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := attached.(*AttachedRuntime); !ok || attached.URI() != started.URI() {
+		t.Fatalf("Get() = %T serving %s, want a runtime attached to %s", attached, attached.URI(), started.URI())
+	}
+
+	_ = live.Close()
+	select {
+	case err := <-ownerClosed:
+		t.Fatalf("owner Close() = %v before the newcomer released its lease", err)
+	case <-time.After(3 * sharedRuntimePollInterval):
+	}
+	if err := newcomer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-ownerClosed; err != nil {
+		t.Fatalf("owner Close() = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "draining", sharedRuntimeEndpointFile)); !os.IsNotExist(err) {
+		t.Fatalf("endpoint file after Close: Stat() error = %v, want not exist", err)
+	}
+}
+
+func TestSharedRuntimeOwnerReleaseTimeout(t *testing.T) {
+	dir := t.TempDir()
+	owner := NewSharedLazyRuntime(BackendInProcess, SharedRuntimeConfig{Dir: dir, Name: "timeout", ReleaseTimeout: 300 * time.Millisecond})
+	if _, err := owner.Get(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	// A lease that is never released, e.g. of a hung process.
+	lockedLease(t, filepath.Join(dir, "timeout", sharedRuntimeLeaseDir), fmt.Sprintf("%d-hung", exitedPID(t)))
+
+	err := owner.Close()
+	if err == nil || !strings.Contains(err.Error(), "still held leases") {
+		t.Fatalf("Close() error = %v, want the release timeout", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "timeout", sharedRuntimeEndpointFile)); !os.IsNotExist(err) {
+		t.Fatalf("endpoint file after Close: Stat() error = %v, want not exist", err)
+	}
+}
+
+func TestSharedRuntimeReplacesStaleEndpoint(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  int
+	}{
+		{name: "exited", pid: exitedPID(t)},
+		// The PID is alive, as if reused by another process, but holds no
+		// lease.
+		{name: "reused", pid: os.Getpid()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := SaveEndpoint(filepath.Join(dir, "stale", sharedRuntimeEndpointFile), Endpoint{
+				Backend:    BackendInProcess,
+				URI:        "127.0.0.1:1",
+				ProjectID:  DefaultProjectID,
+				InstanceID: DefaultInstanceID,
+				PID:        tt.pid,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			runtime := NewSharedLazyRuntime(BackendInProcess, SharedRuntimeConfig{Dir: dir, Name: "stale"})
+			t.Cleanup(func() {
+				if err := runtime.Close(); err != nil {
+					t.Error(err)
+				}
+			})
+			started, err := runtime.Get(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := started.(*inProcessRuntime); !ok {
+				t.Fatalf("Get() = %T, want a new runtime replacing the stale endpoint", started)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package spanemuboost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Snapshot holds all rows of the user tables of a database, read at a single
+// timestamp by [Clients.Snapshot]. Use [Clients.Restore] to put a database
+// back to the state of a snapshot.
+//
+// A Snapshot can be serialized with [encoding/json] and reused across runs
+// against a database with the same schema.
+type Snapshot struct {
+	// ReadTimestamp is the timestamp the rows were read at.
+	ReadTimestamp time.Time
+	// Tables holds the rows of each table in an order where parent and
+	// referenced tables come before the tables that depend on them.
+	Tables []*SnapshotTable
+}
+
+// SnapshotTable holds the rows of one table in a [Snapshot].
+// Generated columns are not included.
+type SnapshotTable struct {
+	// Name is the table name, qualified with its schema if it is not in the
+	// default schema.
+	Name    string
+	Columns []string
+	Types   []*sppb.Type
+	// Rows holds the values of each row in the order of Columns.
+	Rows [][]*structpb.Value
+}
+
+// Snapshot reads all rows of every user table in one read-only transaction,
+// so that the result is consistent at a single timestamp.
+func (c *Clients) Snapshot(ctx context.Context) (*Snapshot, error) {
+	if err := c.requireInformationSchema("Clients.Snapshot"); err != nil {
+		return nil, err
+	}
+	return snapshotDatabase(ctx, c.Client, c.schemaOrDefault().databaseDialect, 0)
+}
+
+// snapshotDatabase reads up to limit rows of every user table, or all rows
+// when limit is 0.
+func snapshotDatabase(ctx context.Context, client *spanner.Client, dialect databasepb.DatabaseDialect, limit int) (*Snapshot, error) {
+	schema, err := loadFixtureSchema(ctx, client, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema for snapshot: %w", err)
+	}
+
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	order := deletionOrder(schema)
+	slices.Reverse(order)
+	snap := &Snapshot{}
+	for _, t := range order {
+		columns := slices.Sorted(maps.Keys(t.columns))
+		columns = slices.DeleteFunc(columns, func(column string) bool { return t.generated[column] })
+		table := &SnapshotTable{Name: t.qualifiedName(), Columns: columns}
+		iter := txn.ReadWithOptions(ctx, table.Name, spanner.AllKeys(), columns, &spanner.ReadOptions{Limit: limit})
+		err := iter.Do(func(r *spanner.Row) error {
+			row := make([]*structpb.Value, r.Size())
+			for i := range row {
+				row[i] = r.ColumnValue(i)
+			}
+			table.Rows = append(table.Rows, row)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table %s: %w", table.Name, err)
+		}
+		for _, field := range iter.Metadata.GetRowType().GetFields() {
+			table.Types = append(table.Types, field.GetType())
+		}
+		snap.Tables = append(snap.Tables, table)
+	}
+
+	snap.ReadTimestamp, err = txn.Timestamp()
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Restore deletes all rows of every user table, as [Clients.ResetData] does,
+// and inserts the rows of snap. The rows are inserted in transactions of up to
+// [WithSetupBatchSize] rows, so a failed Restore can leave the database
+// partially restored.
+func (c *Clients) Restore(ctx context.Context, snap *Snapshot) error {
+	if snap == nil {
+		return fmt.Errorf("snapshot is nil")
+	}
+	if err := c.requireInformationSchema("Clients.Restore"); err != nil {
+		return err
+	}
+	if err := c.ResetData(ctx); err != nil {
+		return err
+	}
+
+	mutations, err := snapshotMutations(snap)
+	if err != nil {
+		return err
+	}
+	return applyMutationChunks(ctx, c.Client, "snapshot rows", mutations, c.schemaOrDefault().setupBatchSizeOrDefault(), nil)
+}
+
+// snapshotMutations returns insert mutations for the rows of snap, in the
+// order of its tables.
+func snapshotMutations(snap *Snapshot) ([]*spanner.Mutation, error) {
+	var mutations []*spanner.Mutation
+	for _, table := range snap.Tables {
+		if len(table.Rows) > 0 && len(table.Types) != len(table.Columns) {
+			return nil, fmt.Errorf("snapshot table %s has %d columns and %d types", table.Name, len(table.Columns), len(table.Types))
+		}
+		for _, row := range table.Rows {
+			if len(row) != len(table.Columns) {
+				return nil, fmt.Errorf("snapshot table %s has a row with %d values, want %d", table.Name, len(row), len(table.Columns))
+			}
+			values := make([]any, len(row))
+			for i, v := range row {
+				values[i] = spanner.GenericColumnValue{Type: table.Types[i], Value: v}
+			}
+			mutations = append(mutations, spanner.Insert(table.Name, table.Columns, values))
+		}
+	}
+	return mutations, nil
+}
+
+// schemaOrDefault returns the schema options the database was bootstrapped
+// with, or the zero options if they are unknown.
+func (c *Clients) schemaOrDefault() *emulatorOptions {
+	if c.schema == nil {
+		return &emulatorOptions{}
+	}
+	return c.schema
+}
+
+// snapshotJSON is the JSON form of a [Snapshot]. Types and values are encoded
+// with protojson.
+type snapshotJSON struct {
+	ReadTimestamp time.Time           `json:"read_timestamp"`
+	Tables        []snapshotTableJSON `json:"tables"`
+}
+
+type snapshotTableJSON struct {
+	Name    string            `json:"name"`
+	Columns []string          `json:"columns"`
+	Types   []json.RawMessage `json:"types"`
+	Rows    []json.RawMessage `json:"rows"`
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (s *Snapshot) MarshalJSON() ([]byte, error) {
+	out := snapshotJSON{ReadTimestamp: s.ReadTimestamp, Tables: make([]snapshotTableJSON, 0, len(s.Tables))}
+	for _, table := range s.Tables {
+		t := snapshotTableJSON{Name: table.Name, Columns: table.Columns}
+		for _, typ := range table.Types {
+			b, err := protojson.Marshal(typ)
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", table.Name, err)
+			}
+			t.Types = append(t.Types, b)
+		}
+		for _, row := range table.Rows {
+			b, err := protojson.Marshal(&structpb.ListValue{Values: row})
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", table.Name, err)
+			}
+			t.Rows = append(t.Rows, b)
+		}
+		out.Tables = append(out.Tables, t)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var in snapshotJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	snap := Snapshot{ReadTimestamp: in.ReadTimestamp}
+	for _, t := range in.Tables {
+		table := &SnapshotTable{Name: t.Name, Columns: t.Columns}
+		for _, b := range t.Types {
+			typ := &sppb.Type{}
+			if err := protojson.Unmarshal(b, typ); err != nil {
+				return fmt.Errorf("table %s: %w", t.Name, err)
+			}
+			table.Types = append(table.Types, typ)
+		}
+		for _, b := range t.Rows {
+			var row structpb.ListValue
+			if err := protojson.Unmarshal(b, &row); err != nil {
+				return fmt.Errorf("table %s: %w", t.Name, err)
+			}
+			table.Rows = append(table.Rows, row.GetValues())
+		}
+		snap.Tables = append(snap.Tables, table)
+	}
+	*s = snap
+	return nil
+}
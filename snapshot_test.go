@@ -0,0 +1,106 @@
+package spanemuboost
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSnapshotJSON(t *testing.T) {
+	want := &Snapshot{
+		ReadTimestamp: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC),
+		Tables: []*SnapshotTable{{
+			Name:    "Singers",
+			Columns: []string{"Name", "SingerId", "Tags"},
+			Types: []*sppb.Type{
+				{Code: sppb.TypeCode_STRING},
+				{Code: sppb.TypeCode_INT64},
+				{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: sppb.TypeCode_STRING}},
+			},
+			Rows: [][]*structpb.Value{
+				{structpb.NewStringValue("Alice"), structpb.NewStringValue("1"), structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("a")}})},
+				{structpb.NewNullValue(), structpb.NewStringValue("2"), structpb.NewNullValue()},
+			},
+		}},
+	}
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var got Snapshot
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+		t.Fatalf("snapshot mismatch after JSON round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestClientsSnapshotRestore(t *testing.T) {
+	clients := SetupEmulatorWithClients(t,
+		WithSetupDDLs([]string{
+			"CREATE TABLE Singers (SingerId INT64, Name STRING(MAX), Upper STRING(MAX) AS (UPPER(Name)) STORED) PRIMARY KEY (SingerId)",
+			"CREATE TABLE Albums (SingerId INT64, AlbumId INT64, Released DATE) PRIMARY KEY (SingerId, AlbumId), INTERLEAVE IN PARENT Singers",
+		}),
+		WithSetupMutations([]*spanner.Mutation{
+			spanner.InsertMap("Singers", map[string]any{"SingerId": 1, "Name": "Alice"}),
+			spanner.InsertMap("Albums", map[string]any{"SingerId": 1, "AlbumId": 10, "Released": civil.Date{Year: 2024, Month: 5, Day: 6}}),
+		}),
+	).Clients
+
+	snap, err := clients.Snapshot(t.Context())
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snap.ReadTimestamp.IsZero() {
+		t.Fatal("Snapshot().ReadTimestamp is zero")
+	}
+
+	// Restore a snapshot that went through JSON, as one reused across runs would.
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Snapshot
+	if err := json.Unmarshal(b, &restored); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = clients.Client.Apply(t.Context(), []*spanner.Mutation{
+		spanner.InsertMap("Singers", map[string]any{"SingerId": 2, "Name": "Bob"}),
+		spanner.Delete("Albums", spanner.AllKeys()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := clients.Restore(t.Context(), &restored); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	var got []string
+	err = clients.Client.Single().Query(t.Context(), spanner.NewStatement(
+		"SELECT FORMAT('%d %s %s %d %t', s.SingerId, s.Name, s.Upper, a.AlbumId, a.Released) FROM Singers s JOIN Albums a USING (SingerId)",
+	)).Do(func(r *spanner.Row) error {
+		var s string
+		if err := r.Column(0, &s); err != nil {
+			return err
+		}
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"1 Alice ALICE 10 2024-05-06"}, got); diff != "" {
+		t.Fatalf("restored rows mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -45,6 +45,13 @@ type Clients struct {
 
 	dropDatabase bool
 	dropInstance bool
+	// inProcess reports that the clients are served by BackendInProcess.
+	inProcess bool
+	// afterClose, if set, runs at the end of the first Close.
+	afterClose func()
+	// schema holds the dialect, schema, and fixture options the database was
+	// bootstrapped with, as returned by schemaOptions. It may be nil.
+	schema *emulatorOptions
 
 	// Pointer-backed to avoid embedding a sync.Once copylock in the exported
 	// Clients struct layout.
@@ -115,6 +122,9 @@ func (c *Clients) Close() error {
 		if c.InstanceClient != nil {
 			errs = append(errs, c.InstanceClient.Close())
 		}
+		if c.afterClose != nil {
+			c.afterClose()
+		}
 		return errors.Join(errs...)
 	})
 }
@@ -136,6 +146,7 @@ func RunEmulator(ctx context.Context, options ...Option) (*Emulator, error) {
 	}
 
 	emu := &Emulator{container: container, opts: opts}
+	enableSchemaTemplates(opts)
 
 	if err = bootstrap(ctx, opts, emu.ClientOptions()...); err != nil {
 		_ = emu.Close()
@@ -160,6 +171,7 @@ func RunEmulatorWithClients(ctx context.Context, options ...Option) (*Env, error
 	}
 
 	emu := &Emulator{container: container, opts: opts}
+	enableSchemaTemplates(opts)
 
 	clients, err := bootstrapAndCreateClients(ctx, emu, opts)
 	if err != nil {
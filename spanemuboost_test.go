@@ -227,6 +227,35 @@ func TestSetupEmulatorAndSetupClients(t *testing.T) {
 	})
 }
 
+func TestSetupClientsWithSetupMutations(t *testing.T) {
+	type row struct {
+		PK  string `spanner:"pk"`
+		Col int64  `spanner:"col"`
+	}
+
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	clients := SetupClients(t, emu,
+		WithRandomDatabaseID(),
+		WithSetupDDLs([]string{"CREATE TABLE tbl (pk STRING(MAX), col INT64) PRIMARY KEY (pk)"}),
+		WithSetupStructs("tbl", []row{{"bar", 2}, {"foo", 1}}),
+		WithSetupMutations([]*spanner.Mutation{
+			spanner.InsertMap("tbl", map[string]any{"pk": "baz", "col": 3}),
+		}),
+		// DMLs run after mutations, so they can see the inserted rows.
+		WithSetupRawDMLs([]string{"UPDATE tbl SET col = col * 10 WHERE pk = 'baz'"}),
+	)
+
+	var got []*row
+	err := spanner.SelectAll(clients.Client.Single().Query(t.Context(), spanner.NewStatement(`SELECT pk, col FROM tbl ORDER BY pk`)), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*row{{"bar", 2}, {"baz", 30}, {"foo", 1}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestWithRandomIDImpliesCreation(t *testing.T) {
 	ddls := []string{"CREATE TABLE tbl (pk STRING(MAX)) PRIMARY KEY (pk)"}
 
@@ -0,0 +1,253 @@
+package spanemuboost
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+)
+
+// sqlStatement is one statement produced by splitStatements.
+// Line is the 1-based line on which the statement text starts.
+type sqlStatement struct {
+	SQL  string
+	Line int
+}
+
+// splitStatements splits src into statements separated by top-level semicolons.
+//
+// Comments are stripped, and semicolons inside comments, string literals, and
+// quoted identifiers do not terminate a statement. GoogleSQL supports `--`, `#`,
+// and `/* */` comments, single/double/triple-quoted strings with optional r/b
+// prefixes, and backtick identifiers. PostgreSQL supports `--` and nested
+// `/* */` comments, standard and E'...' strings, double-quoted identifiers, and
+// dollar-quoted strings.
+func splitStatements(src string, dialect databasepb.DatabaseDialect) ([]sqlStatement, error) {
+	s := &statementSplitter{
+		src:        src,
+		line:       1,
+		postgreSQL: dialect == databasepb.DatabaseDialect_POSTGRESQL,
+	}
+	return s.split()
+}
+
+type statementSplitter struct {
+	src        string
+	pos        int
+	line       int
+	postgreSQL bool
+
+	buf       strings.Builder
+	startLine int
+	stmts     []sqlStatement
+}
+
+// syntaxError reports a lexical error at a 1-based line.
+type syntaxError struct {
+	line int
+	msg  string
+}
+
+func (e *syntaxError) Error() string { return fmt.Sprintf("line %d: %s", e.line, e.msg) }
+
+func (s *statementSplitter) split() ([]sqlStatement, error) {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == ';':
+			s.pos++
+			s.flush()
+		case c == '-' && s.peek(1) == '-', c == '#' && !s.postgreSQL:
+			s.skipLineComment()
+		case c == '/' && s.peek(1) == '*':
+			if err := s.skipBlockComment(); err != nil {
+				return nil, err
+			}
+		case c == '\'' || c == '"' || c == '`':
+			if err := s.consumeQuoted(); err != nil {
+				return nil, err
+			}
+		case c == '$' && s.postgreSQL:
+			if err := s.consumeDollarQuoted(); err != nil {
+				return nil, err
+			}
+		default:
+			s.write(s.pos, s.pos+1)
+		}
+	}
+	s.flush()
+	return s.stmts, nil
+}
+
+func (s *statementSplitter) peek(offset int) byte {
+	if s.pos+offset < len(s.src) {
+		return s.src[s.pos+offset]
+	}
+	return 0
+}
+
+// write appends src[from:to] to the current statement and advances past it.
+func (s *statementSplitter) write(from, to int) {
+	text := s.src[from:to]
+	if s.startLine == 0 && strings.TrimSpace(text) != "" {
+		// Whitespace is written one byte at a time, so a non-blank write
+		// always starts on the current line.
+		s.startLine = s.line
+	}
+	s.buf.WriteString(text)
+	s.line += strings.Count(text, "\n")
+	s.pos = to
+}
+
+// skip advances past src[from:to] without adding it to the statement.
+func (s *statementSplitter) skip(from, to int) {
+	s.line += strings.Count(s.src[from:to], "\n")
+	s.pos = to
+}
+
+func (s *statementSplitter) flush() {
+	sql := strings.TrimSpace(s.buf.String())
+	if sql != "" {
+		s.stmts = append(s.stmts, sqlStatement{SQL: sql, Line: s.startLine})
+	}
+	s.buf.Reset()
+	s.startLine = 0
+}
+
+func (s *statementSplitter) skipLineComment() {
+	end := strings.IndexByte(s.src[s.pos:], '\n')
+	if end < 0 {
+		s.skip(s.pos, len(s.src))
+		return
+	}
+	// Keep the newline so tokens on either side of the comment stay separated.
+	s.skip(s.pos, s.pos+end)
+}
+
+func (s *statementSplitter) skipBlockComment() error {
+	startLine := s.line
+	depth := 0
+	i := s.pos
+	for i < len(s.src) {
+		switch {
+		case strings.HasPrefix(s.src[i:], "/*"):
+			depth++
+			i += 2
+			if !s.postgreSQL && depth > 1 {
+				// GoogleSQL block comments do not nest.
+				depth = 1
+			}
+		case strings.HasPrefix(s.src[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				s.skip(s.pos, i)
+				s.buf.WriteByte(' ')
+				return nil
+			}
+		default:
+			i++
+		}
+	}
+	return &syntaxError{line: startLine, msg: "unterminated block comment"}
+}
+
+// consumeQuoted consumes a string literal or quoted identifier starting at the
+// current quote character. Literal prefixes such as GoogleSQL r/b or
+// PostgreSQL E have already been written as ordinary characters.
+func (s *statementSplitter) consumeQuoted() error {
+	startLine := s.line
+	quote := s.src[s.pos]
+	backslashEscapes := s.backslashEscapes()
+	delim := string(quote)
+	if !s.postgreSQL && quote != '`' && strings.HasPrefix(s.src[s.pos:], strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+
+	i := s.pos + len(delim)
+	for i < len(s.src) {
+		c := s.src[i]
+		switch {
+		case c == '\\' && backslashEscapes:
+			i += 2
+			continue
+		case c == '\n' && len(delim) == 1 && !s.postgreSQL:
+			// GoogleSQL single-quoted literals cannot span lines.
+			return &syntaxError{line: startLine, msg: fmt.Sprintf("unterminated %s", quotedKind(quote))}
+		case strings.HasPrefix(s.src[i:], delim):
+			if s.postgreSQL && s.peekAt(i+1) == quote {
+				// PostgreSQL escapes a quote by doubling it.
+				i += 2
+				continue
+			}
+			s.write(s.pos, i+len(delim))
+			return nil
+		}
+		i++
+	}
+	return &syntaxError{line: startLine, msg: fmt.Sprintf("unterminated %s", quotedKind(quote))}
+}
+
+func (s *statementSplitter) peekAt(i int) byte {
+	if i < len(s.src) {
+		return s.src[i]
+	}
+	return 0
+}
+
+// backslashEscapes reports whether a backslash inside the quoted token at the
+// current position protects the following character from ending the token.
+//
+// GoogleSQL lexes every string, bytes, and raw literal this way (a raw literal
+// cannot end with an odd number of backslashes). PostgreSQL only does so for
+// E'...' strings, which are detected from the identifier characters written just
+// before the opening quote.
+func (s *statementSplitter) backslashEscapes() bool {
+	if !s.postgreSQL {
+		return true
+	}
+	if s.src[s.pos] != '\'' || s.pos == 0 {
+		return false
+	}
+	prev := s.src[s.pos-1]
+	return (prev == 'e' || prev == 'E') && (s.pos < 2 || !isSQLIdentChar(s.src[s.pos-2]))
+}
+
+func (s *statementSplitter) consumeDollarQuoted() error {
+	startLine := s.line
+	end := s.pos + 1
+	for end < len(s.src) && isSQLIdentChar(s.src[end]) {
+		end++
+	}
+	if end >= len(s.src) || s.src[end] != '$' || (end > s.pos+1 && isDigit(s.src[s.pos+1])) {
+		// A positional parameter such as $1, not a dollar quote.
+		s.write(s.pos, end)
+		return nil
+	}
+	tag := s.src[s.pos : end+1]
+	closeAt := strings.Index(s.src[end+1:], tag)
+	if closeAt < 0 {
+		return &syntaxError{line: startLine, msg: fmt.Sprintf("unterminated dollar-quoted string %s", tag)}
+	}
+	s.write(s.pos, end+1+closeAt+len(tag))
+	return nil
+}
+
+func quotedKind(quote byte) string {
+	switch quote {
+	case '`':
+		return "quoted identifier"
+	case '"':
+		return "double-quoted string or identifier"
+	default:
+		return "string literal"
+	}
+}
+
+func isSQLIdentChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,121 @@
+package spanemuboost
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitStatementsGoogleSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []sqlStatement
+	}{
+		{
+			name: "trailing statement without semicolon",
+			src:  "CREATE TABLE a (id INT64) PRIMARY KEY (id);\nCREATE TABLE b (id INT64) PRIMARY KEY (id)",
+			want: []sqlStatement{
+				{SQL: "CREATE TABLE a (id INT64) PRIMARY KEY (id)", Line: 1},
+				{SQL: "CREATE TABLE b (id INT64) PRIMARY KEY (id)", Line: 2},
+			},
+		},
+		{
+			name: "comments are stripped and do not split",
+			src: `-- leading comment; not a statement
+# hash comment;
+CREATE TABLE a (
+  id INT64, /* block; comment */
+  name STRING(MAX) -- trailing; comment
+) PRIMARY KEY (id);
+/* only a comment; */
+`,
+			want: []sqlStatement{
+				{SQL: "CREATE TABLE a (\n  id INT64,  \n  name STRING(MAX) \n) PRIMARY KEY (id)", Line: 3},
+			},
+		},
+		{
+			name: "semicolons in literals and identifiers",
+			src: "INSERT INTO `a;b` (s, t, r, b) VALUES ('x;y', \"it\\\"s;\", r'\\';', b'\\x00;');" +
+				"\n\nINSERT INTO c (s) VALUES ('''multi;\nline''')",
+			want: []sqlStatement{
+				{SQL: "INSERT INTO `a;b` (s, t, r, b) VALUES ('x;y', \"it\\\"s;\", r'\\';', b'\\x00;')", Line: 1},
+				{SQL: "INSERT INTO c (s) VALUES ('''multi;\nline''')", Line: 3},
+			},
+		},
+		{
+			name: "dash inside string is not a comment",
+			src:  "SELECT '--not a comment;'; SELECT 2",
+			want: []sqlStatement{
+				{SQL: "SELECT '--not a comment;'", Line: 1},
+				{SQL: "SELECT 2", Line: 1},
+			},
+		},
+		{
+			name: "empty statements are dropped",
+			src:  ";;\n\n;",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitStatements(tt.src, databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL)
+			if err != nil {
+				t.Fatalf("splitStatements() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("splitStatements() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSplitStatementsPostgreSQL(t *testing.T) {
+	src := `/* outer /* nested; */ still comment; */
+CREATE TABLE "a;b" (id bigint PRIMARY KEY, s text);
+INSERT INTO "a;b" (id, s) VALUES (1, 'it''s; fine'), (2, E'esc\'; aped');
+INSERT INTO "a;b" (id, s) VALUES ($1, $tag$dollar; quoted$tag$);
+# not a comment in PostgreSQL`
+	got, err := splitStatements(src, databasepb.DatabaseDialect_POSTGRESQL)
+	if err != nil {
+		t.Fatalf("splitStatements() error = %v", err)
+	}
+	want := []sqlStatement{
+		{SQL: `CREATE TABLE "a;b" (id bigint PRIMARY KEY, s text)`, Line: 2},
+		{SQL: `INSERT INTO "a;b" (id, s) VALUES (1, 'it''s; fine'), (2, E'esc\'; aped')`, Line: 3},
+		{SQL: `INSERT INTO "a;b" (id, s) VALUES ($1, $tag$dollar; quoted$tag$)`, Line: 4},
+		{SQL: `# not a comment in PostgreSQL`, Line: 5},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("splitStatements() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSplitStatementsReportsUnterminatedTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		dialect databasepb.DatabaseDialect
+		want    string
+	}{
+		{name: "string", src: "SELECT 1;\nSELECT 'open", want: "line 2: unterminated string literal"},
+		{name: "single-quoted string across lines", src: "SELECT 'a\nb'", want: "line 1: unterminated string literal"},
+		{name: "backtick", src: "\n\nSELECT `open", want: "line 3: unterminated quoted identifier"},
+		{name: "block comment", src: "SELECT 1 /* open", want: "line 1: unterminated block comment"},
+		{name: "dollar quote", src: "SELECT $x$open", dialect: databasepb.DatabaseDialect_POSTGRESQL, want: "line 1: unterminated dollar-quoted string $x$"},
+		{name: "nested comment", src: "/* a /* b */", dialect: databasepb.DatabaseDialect_POSTGRESQL, want: "line 1: unterminated block comment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := splitStatements(tt.src, tt.dialect)
+			if err == nil {
+				t.Fatal("splitStatements() error = nil, want non-nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("splitStatements() error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
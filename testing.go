@@ -147,6 +147,42 @@ func setupOmniWithClients(tb testing.TB, options ...Option) *RuntimeEnv {
 	}, "omni env")
 }
 
+func setupInProcess(tb testing.TB, options ...Option) Runtime {
+	return setupWithCleanup(tb, func(ctx context.Context) (Runtime, error) {
+		return runInProcess(ctx, options...)
+	}, "in-process runtime")
+}
+
+func setupInProcessWithClients(tb testing.TB, options ...Option) *RuntimeEnv {
+	return setupWithCleanup(tb, func(ctx context.Context) (*RuntimeEnv, error) {
+		return runInProcessWithClients(ctx, options...)
+	}, "in-process env")
+}
+
+func setupEmulatorBinary(tb testing.TB, options ...Option) Runtime {
+	return setupWithCleanup(tb, func(ctx context.Context) (Runtime, error) {
+		return runEmulatorBinary(ctx, options...)
+	}, "emulator binary")
+}
+
+func setupEmulatorBinaryWithClients(tb testing.TB, options ...Option) *RuntimeEnv {
+	return setupWithCleanup(tb, func(ctx context.Context) (*RuntimeEnv, error) {
+		return runEmulatorBinaryWithClients(ctx, options...)
+	}, "emulator binary env")
+}
+
+func setupProvided(tb testing.TB, backend Backend, provider BackendProvider, options ...Option) Runtime {
+	return setupWithCleanup(tb, func(ctx context.Context) (Runtime, error) {
+		return runProvided(ctx, backend, provider, options...)
+	}, string(backend)+" runtime")
+}
+
+func setupProvidedWithClients(tb testing.TB, backend Backend, provider BackendProvider, options ...Option) *RuntimeEnv {
+	return setupWithCleanup(tb, func(ctx context.Context) (*RuntimeEnv, error) {
+		return runProvidedWithClients(ctx, backend, provider, options...)
+	}, string(backend)+" env")
+}
+
 // SetupEmulatorWithClients starts a Cloud Spanner Emulator with clients and registers
 // cleanup via [testing.TB.Cleanup]. It calls [testing.TB.Fatal] on setup error.
 // Use [RunEmulatorWithClients] if you need a [context.Context] or are not in a test.
@@ -0,0 +1,292 @@
+package spanemuboost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/grpc/status"
+)
+
+// ValidateDDLConfig configures [ValidateDDL] and [ValidateDDLFromConfig].
+type ValidateDDLConfig struct {
+	// EndpointFile is the backend to validate against. When it is empty, the
+	// endpoint configured by env vars is used, and an emulator is started if
+	// none is configured. Only [ValidateDDLFromConfig] uses it.
+	EndpointFile string
+	// Dialect is the dialect of the throwaway database.
+	Dialect databasepb.DatabaseDialect
+	// MigrationsDir is a directory of migration files, applied in version
+	// order as [WithMigrations] does, before Files.
+	MigrationsDir string
+	// Files are DDL files, applied in order.
+	Files []string
+	// PrintSchema includes the normalized schema of the database in the
+	// report when all statements are applied.
+	PrintSchema bool
+	// JSON makes [ValidateDDLFromConfig] write the report as JSON.
+	JSON bool
+}
+
+// ValidateDDLReport is the result of [ValidateDDL].
+type ValidateDDLReport struct {
+	// OK reports whether every statement was applied.
+	OK bool `json:"ok"`
+	// Statements is the number of statements read from the files.
+	Statements int `json:"statements"`
+	// Failure describes the first statement that failed, if any.
+	Failure *ValidateDDLFailure `json:"failure,omitempty"`
+	// Schema is the normalized DDL of the database, as written by
+	// [AssertSchema], when ValidateDDLConfig.PrintSchema is set.
+	Schema []string `json:"schema,omitempty"`
+}
+
+// ValidateDDLFailure is the first statement rejected by [ValidateDDL].
+type ValidateDDLFailure struct {
+	// Source is the file:line of the statement, or the file alone when the
+	// statement cannot be determined.
+	Source string `json:"source,omitempty"`
+	// Statement is the text of the statement, or empty if it cannot be
+	// determined.
+	Statement string `json:"statement,omitempty"`
+	// Message describes why the statement was rejected.
+	Message string `json:"message"`
+}
+
+// ValidateDDL creates a throwaway database on runtime, applies the migrations
+// and files of cfg to it, and reports the first statement that fails to split
+// or to apply. As at runtime, each migration is applied as its own
+// UpdateDatabaseDdl operation, and the files are applied together after them.
+// The database is dropped before ValidateDDL returns.
+//
+// Rejected statements are reported in the returned report, not as an error;
+// the error is reserved for failures to read the files or to reach runtime.
+func ValidateDDL(ctx context.Context, runtime RuntimeHandle, cfg ValidateDDLConfig) (report *ValidateDDLReport, err error) {
+	if len(cfg.Files) == 0 && cfg.MigrationsDir == "" {
+		return nil, fmt.Errorf("spanemuboost: ValidateDDL: no DDL files or migrations directory")
+	}
+	migrations, files, err := readValidateDDLFiles(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("spanemuboost: ValidateDDL: %w", err)
+	}
+
+	opts := &emulatorOptions{databaseDialect: cfg.Dialect}
+	statements := 0
+	for i := range migrations {
+		m := &migrations[i]
+		var failure *ValidateDDLFailure
+		if m.ddls, m.sources, failure, err = splitValidateDDLFile(m.file, cfg.Dialect); err != nil {
+			return nil, err
+		}
+		if failure == nil && len(m.ddls) == 0 {
+			failure = &ValidateDDLFailure{Source: m.file.name, Message: "migration file contains no statements"}
+		}
+		if failure != nil {
+			return &ValidateDDLReport{Statements: statements, Failure: failure}, nil
+		}
+		statements += len(m.ddls)
+	}
+	opts.migrations = migrations
+	for _, file := range files {
+		ddls, sources, failure, err := splitValidateDDLFile(file, cfg.Dialect)
+		if err != nil {
+			return nil, err
+		}
+		if failure != nil {
+			return &ValidateDDLReport{Statements: statements, Failure: failure}, nil
+		}
+		opts.setupDDLs = append(opts.setupDDLs, ddls...)
+		opts.setupDDLSources = append(opts.setupDDLSources, sources...)
+		statements += len(ddls)
+	}
+
+	clients, err := OpenClients(ctx, runtime,
+		WithRandomDatabaseID(),
+		WithDatabaseDialect(cfg.Dialect),
+		WithSetupDDLs(nil),
+		ForceSchemaTeardown(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, clients.Close())
+	}()
+
+	report = &ValidateDDLReport{OK: true, Statements: statements}
+	if statements > 0 {
+		opts.projectID, opts.instanceID, opts.databaseID = clients.ProjectID, clients.InstanceID, clients.DatabaseID
+		if err := updateDDLs(ctx, opts, clients.DatabaseClient); err != nil {
+			var stmtErr *SetupStatementError
+			if !errors.As(err, &stmtErr) || ctx.Err() != nil {
+				return nil, err
+			}
+			report.OK = false
+			report.Failure = &ValidateDDLFailure{
+				Source:    stmtErr.Source,
+				Statement: stmtErr.Statement,
+				Message:   status.Convert(stmtErr.Err).Message(),
+			}
+			return report, nil
+		}
+	}
+	if cfg.PrintSchema {
+		if report.Schema, _, err = databaseDDL(ctx, clients); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+func readValidateDDLFiles(cfg ValidateDDLConfig) ([]migration, []setupFile, error) {
+	var migrations []migration
+	if cfg.MigrationsDir != "" {
+		var err error
+		migrations, err = readMigrations(os.DirFS(cfg.MigrationsDir), ".", LatestMigrationVersion)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrations %q: %w", cfg.MigrationsDir, err)
+		}
+		for i := range migrations {
+			migrations[i].file.name = filepath.Join(cfg.MigrationsDir, migrations[i].file.name)
+		}
+	}
+	var files []setupFile
+	for _, name := range cfg.Files {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, setupFile{name: name, content: string(content)})
+	}
+	return migrations, files, nil
+}
+
+// splitValidateDDLFile splits file into statements and their file:line
+// sources, or returns the failure for a syntax error.
+func splitValidateDDLFile(file setupFile, dialect databasepb.DatabaseDialect) (ddls, sources []string, failure *ValidateDDLFailure, err error) {
+	split, err := splitStatements(file.content, dialect)
+	if err != nil {
+		var syntaxErr *syntaxError
+		if !errors.As(err, &syntaxErr) {
+			return nil, nil, nil, fmt.Errorf("spanemuboost: ValidateDDL: %s: %w", file.name, err)
+		}
+		return nil, nil, &ValidateDDLFailure{
+			Source:  fmt.Sprintf("%s:%d", file.name, syntaxErr.line),
+			Message: syntaxErr.msg,
+		}, nil
+	}
+	for _, stmt := range split {
+		ddls = append(ddls, stmt.SQL)
+		sources = append(sources, fmt.Sprintf("%s:%d", file.name, stmt.Line))
+	}
+	return ddls, sources, nil, nil
+}
+
+// Write writes r to w as text, or as indented JSON when asJSON is set.
+func (r *ValidateDDLReport) Write(w io.Writer, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+
+	var b strings.Builder
+	if r.Failure != nil {
+		if r.Failure.Source != "" {
+			fmt.Fprintf(&b, "%s: ", r.Failure.Source)
+		}
+		fmt.Fprintf(&b, "%s\n", r.Failure.Message)
+		if r.Failure.Statement != "" {
+			fmt.Fprintf(&b, "\t%s\n", strings.ReplaceAll(r.Failure.Statement, "\n", "\n\t"))
+		}
+	} else {
+		fmt.Fprintf(&b, "ok: %d statements applied\n", r.Statements)
+	}
+	if len(r.Schema) > 0 {
+		fmt.Fprintf(&b, "\n%s", formatGoldenDDL(r.Schema))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ValidateDDLFromConfig runs [ValidateDDL] against the backend of
+// ValidateDDLConfig.EndpointFile, the endpoint configured by env vars, or a
+// newly started emulator, in that order, and writes the report to w.
+func ValidateDDLFromConfig(ctx context.Context, cfg ValidateDDLConfig, w io.Writer) (*ValidateDDLReport, error) {
+	var runtime Runtime
+	var err error
+	if cfg.EndpointFile != "" || EndpointConfigured() {
+		runtime, err = attachFromEndpointFile(cfg.EndpointFile)
+	} else {
+		runtime, err = Run(ctx, BackendEmulator, EnableInstanceAutoConfigOnly())
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		logCloseError("close runtime after validate-ddl", runtime.Close())
+	}()
+
+	report, err := ValidateDDL(ctx, runtime, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return report, report.Write(w, cfg.JSON)
+}
+
+// ParseValidateDDLArgs parses `spanemuboost validate-ddl [--endpoint-file path]
+// [--dialect googlesql|postgresql] [--migrations dir] [--print-schema] [--json] [file]...`.
+func ParseValidateDDLArgs(args []string) (ValidateDDLConfig, error) {
+	cfg := ValidateDDLConfig{}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--endpoint-file", "-o":
+			if i+1 >= len(args) {
+				return ValidateDDLConfig{}, fmt.Errorf("--endpoint-file requires a value")
+			}
+			cfg.EndpointFile = args[i+1]
+			i++
+		case "--dialect":
+			if i+1 >= len(args) {
+				return ValidateDDLConfig{}, fmt.Errorf("--dialect requires a value")
+			}
+			switch strings.ToLower(args[i+1]) {
+			case "googlesql", "google_standard_sql":
+				cfg.Dialect = databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL
+			case "postgresql", "pg":
+				cfg.Dialect = databasepb.DatabaseDialect_POSTGRESQL
+			default:
+				return ValidateDDLConfig{}, fmt.Errorf("unknown dialect %q: must be googlesql or postgresql", args[i+1])
+			}
+			i++
+		case "--migrations":
+			if i+1 >= len(args) {
+				return ValidateDDLConfig{}, fmt.Errorf("--migrations requires a value")
+			}
+			cfg.MigrationsDir = args[i+1]
+			i++
+		case "--print-schema":
+			cfg.PrintSchema = true
+		case "--json":
+			cfg.JSON = true
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				return ValidateDDLConfig{}, fmt.Errorf("unknown argument %q", args[i])
+			}
+			cfg.Files = append(cfg.Files, args[i])
+		}
+	}
+	if len(cfg.Files) == 0 && cfg.MigrationsDir == "" {
+		return ValidateDDLConfig{}, fmt.Errorf("usage: spanemuboost validate-ddl [--endpoint-file path] [--dialect googlesql|postgresql] [--migrations dir] [--print-schema] [--json] [file]...")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,166 @@
+package spanemuboost
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeValidateDDLFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseValidateDDLArgs(t *testing.T) {
+	cfg, err := ParseValidateDDLArgs([]string{"--dialect", "postgresql", "--migrations", "db/migrations", "--print-schema", "--json", "a.sql", "b.sql"})
+	if err != nil {
+		t.Fatalf("ParseValidateDDLArgs() error = %v", err)
+	}
+	want := ValidateDDLConfig{
+		Dialect:       databasepb.DatabaseDialect_POSTGRESQL,
+		MigrationsDir: "db/migrations",
+		Files:         []string{"a.sql", "b.sql"},
+		PrintSchema:   true,
+		JSON:          true,
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Fatalf("ParseValidateDDLArgs() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, args := range [][]string{nil, {"--json"}, {"--dialect", "mysql", "a.sql"}, {"--bogus", "a.sql"}} {
+		if _, err := ParseValidateDDLArgs(args); err == nil {
+			t.Errorf("ParseValidateDDLArgs(%q) error = nil, want error", args)
+		}
+	}
+}
+
+func TestValidateDDLReportsSyntaxErrorWithoutRuntime(t *testing.T) {
+	path := writeValidateDDLFile(t, t.TempDir(), "schema.sql", "CREATE TABLE t (pk INT64) PRIMARY KEY (pk);\nSELECT 'unterminated\n")
+
+	// The files are split before the runtime is used, so a nil runtime is
+	// never reached.
+	report, err := ValidateDDL(t.Context(), nil, ValidateDDLConfig{Files: []string{path}})
+	if err != nil {
+		t.Fatalf("ValidateDDL() error = %v", err)
+	}
+	if report.OK || report.Failure == nil || report.Failure.Source != path+":2" {
+		t.Fatalf("ValidateDDL() = %+v, want a failure at %s:2", report, path)
+	}
+}
+
+func TestValidateDDLReportsEmptyMigrationWithoutRuntime(t *testing.T) {
+	dir := t.TempDir()
+	writeValidateDDLFile(t, dir, "001_init.sql", "CREATE TABLE t (pk INT64) PRIMARY KEY (pk);\n")
+	writeValidateDDLFile(t, dir, "002_empty.sql", "-- nothing yet\n")
+
+	// WithMigrations rejects empty migration files, so ValidateDDL does too.
+	report, err := ValidateDDL(t.Context(), nil, ValidateDDLConfig{MigrationsDir: dir})
+	if err != nil {
+		t.Fatalf("ValidateDDL() error = %v", err)
+	}
+	if want := filepath.Join(dir, "002_empty.sql"); report.OK || report.Failure == nil || report.Failure.Source != want || report.Statements != 1 {
+		t.Fatalf("ValidateDDL() = %+v, want a failure at %s after 1 statement", report, want)
+	}
+}
+
+func TestValidateDDLAppliesMigrationsSeparately(t *testing.T) {
+	runtime := Setup(t, BackendInProcess, EnableInstanceAutoConfigOnly())
+	dir := t.TempDir()
+	migrations := filepath.Join(dir, "migrations")
+	if err := os.Mkdir(migrations, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeValidateDDLFile(t, migrations, "001_init.sql", "CREATE TABLE t (pk INT64 NOT NULL) PRIMARY KEY (pk);\n")
+	writeValidateDDLFile(t, migrations, "002_col.sql", "ALTER TABLE t ADD COLUMN col INT64;\n\nALTER TABLE missing ADD COLUMN col INT64;\n")
+	extra := writeValidateDDLFile(t, dir, "extra.sql", "CREATE TABLE u (pk INT64 NOT NULL) PRIMARY KEY (pk);\n")
+
+	report, err := ValidateDDL(t.Context(), runtime, ValidateDDLConfig{MigrationsDir: migrations, Files: []string{extra}})
+	if err != nil {
+		t.Fatalf("ValidateDDL() error = %v", err)
+	}
+	if want := filepath.Join(migrations, "002_col.sql") + ":3"; report.OK || report.Failure == nil || report.Failure.Source != want {
+		t.Fatalf("ValidateDDL() = %+v, want a failure at %s", report, want)
+	}
+	if report.Statements != 4 {
+		t.Fatalf("Statements = %d, want 4", report.Statements)
+	}
+}
+
+func TestValidateDDLReportWrite(t *testing.T) {
+	report := &ValidateDDLReport{
+		Statements: 2,
+		Failure: &ValidateDDLFailure{
+			Source:    "schema.sql:3",
+			Statement: "CREATE INDEX idx\nON missing (col)",
+			Message:   "Table not found: missing",
+		},
+	}
+
+	var text bytes.Buffer
+	if err := report.Write(&text, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "schema.sql:3: Table not found: missing\n\tCREATE INDEX idx\n\tON missing (col)\n"
+	if diff := cmp.Diff(want, text.String()); diff != "" {
+		t.Fatalf("text report mismatch (-want +got):\n%s", diff)
+	}
+
+	var data bytes.Buffer
+	if err := report.Write(&data, true); err != nil {
+		t.Fatal(err)
+	}
+	var got ValidateDDLReport
+	if err := json.Unmarshal(data.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if diff := cmp.Diff(report, &got); diff != "" {
+		t.Fatalf("JSON report mismatch after round trip (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(data.String(), `"ok": false`) {
+		t.Fatalf("JSON report = %s, want ok to be present when false", data.String())
+	}
+}
+
+func TestValidateDDL(t *testing.T) {
+	emu := SetupEmulator(t, EnableInstanceAutoConfigOnly())
+	dir := t.TempDir()
+	migrations := filepath.Join(dir, "migrations")
+	if err := os.Mkdir(migrations, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeValidateDDLFile(t, migrations, "001_init.sql", "CREATE TABLE t (pk INT64) PRIMARY KEY (pk);\n")
+	good := writeValidateDDLFile(t, dir, "good.sql", "CREATE INDEX t_by_pk ON t (pk DESC);\n")
+	bad := writeValidateDDLFile(t, dir, "bad.sql", "CREATE TABLE u (pk INT64) PRIMARY KEY (pk);\n\nCREATE INDEX u_by_col ON u (col);\n")
+
+	report, err := ValidateDDL(t.Context(), emu, ValidateDDLConfig{MigrationsDir: migrations, Files: []string{good}, PrintSchema: true})
+	if err != nil {
+		t.Fatalf("ValidateDDL() error = %v", err)
+	}
+	if !report.OK || report.Statements != 2 || len(report.Schema) != 2 {
+		t.Fatalf("ValidateDDL() = %+v, want 2 statements applied and 2 schema statements", report)
+	}
+
+	report, err = ValidateDDL(t.Context(), emu, ValidateDDLConfig{MigrationsDir: migrations, Files: []string{bad}})
+	if err != nil {
+		t.Fatalf("ValidateDDL() error = %v", err)
+	}
+	if report.OK || report.Failure == nil {
+		t.Fatalf("ValidateDDL() = %+v, want a failure", report)
+	}
+	if got, want := report.Failure.Source, bad+":3"; got != want {
+		t.Fatalf("failure source = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(report.Failure.Statement, "CREATE INDEX u_by_col") {
+		t.Fatalf("failure statement = %q, want the CREATE INDEX", report.Failure.Statement)
+	}
+}